@@ -0,0 +1,138 @@
+package sugar
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// packagePath is the import path of sugar, which prefixes the names of all of its funcs
+var packagePath = reflect.TypeOf(logger{}).PkgPath()
+
+// helpers holds the names of the funcs that have been marked with Helper
+var helpers sync.Map
+
+// moduleRoots caches the module root of each directory that a line has been logged from
+var moduleRoots sync.Map
+
+// isRelative prints the locations of log lines relative to the module root, instead of by their file name
+var isRelative bool
+
+// isShowingGoroutines prints the id of the goroutine that logged each line after its location
+var isShowingGoroutines bool
+
+func init() {
+	flag.BoolVar(&isRelative, "sugar.relative", false, "print the locations of log lines relative to the module root")
+	flag.BoolVar(&isShowingGoroutines, "sugar.goroutines", false, "print the id of the goroutine that logged each line")
+}
+
+// caller is the location in the source that a line was logged from, and the goroutine that logged it
+type caller struct {
+	file      string
+	line      int
+	goroutine int
+}
+
+// String prints the location as `file.go:12`, or as `path/to/file.go:12` relative to the module root if the
+// `-sugar.relative` flag is set. The goroutine is printed after it, as `file.go:12 goroutine 7`, if the
+// `-sugar.goroutines` flag was set when the line was logged.
+func (c caller) String() string {
+	file := filepath.Base(c.file)
+	if isRelative {
+		if relative, err := filepath.Rel(moduleRoot(filepath.Dir(c.file)), c.file); err == nil {
+			file = filepath.ToSlash(relative)
+		}
+	}
+	if c.goroutine > 0 {
+		return fmt.Sprintf("%s:%d goroutine %d", file, c.line, c.goroutine)
+	}
+	return fmt.Sprintf("%s:%d", file, c.line)
+}
+
+// Helper marks the func that calls it as a helper, like testing.T.Helper, so that lines that are logged from inside
+// of it are tagged with the location that it was called from instead
+func (log Log) Helper() {
+	markHelper()
+}
+
+// markHelper marks the func that called the func that called it as a helper
+func markHelper() {
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(3, pcs) > 0 {
+		frame, _ := runtime.CallersFrames(pcs).Next()
+		helpers.Store(frame.Function, true)
+	}
+}
+
+// callerOf returns the location of the first func on the stack that is outside of sugar, the runtime and log/slog, and
+// isn't a helper. If every func outside of sugar is a helper it returns the first helper, and if there aren't any funcs
+// outside of sugar at all, eg. for lines that sugar logs from its own goroutine, it returns no location.
+func callerOf() caller {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	var firstHelper, at caller
+	for {
+		frame, more := frames.Next()
+		isOutside := !strings.HasPrefix(frame.Function, packagePath+".") && !strings.HasPrefix(frame.Function, "runtime.") &&
+			!strings.HasPrefix(frame.Function, "log/slog.")
+		if _, isHelper := helpers.Load(frame.Function); isOutside && !isHelper {
+			at = caller{file: frame.File, line: frame.Line}
+			break
+		} else if isOutside && firstHelper.file == "" {
+			firstHelper = caller{file: frame.File, line: frame.Line}
+		}
+		if !more {
+			at = firstHelper
+			break
+		}
+	}
+	if isShowingGoroutines && at.file != "" {
+		at.goroutine = goroutineID()
+	}
+	return at
+}
+
+// logAt logs into l tagged with a location, instead of with the location that called it, if l was made by NewLogger
+func logAt(l Logger, at caller, s interface{}, args ...interface{}) {
+	if l, ok := l.(*logger); ok {
+		l.logAt(at, s, args...)
+	} else {
+		l.Log(s, args...)
+	}
+}
+
+// goroutineID returns the id of the goroutine that calls it, which is in the header of its stack trace, eg.
+// `goroutine 7 [running]:`
+func goroutineID() int {
+	buffer := make([]byte, 64)
+	fields := strings.Fields(string(buffer[:runtime.Stack(buffer, false)]))
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.Atoi(fields[1])
+	return id
+}
+
+// moduleRoot returns the closest directory to dir that has a go.mod file in it, or dir if none of them do
+func moduleRoot(dir string) string {
+	if root, ok := moduleRoots.Load(dir); ok {
+		return root.(string)
+	}
+	root := dir
+	for parent := dir; ; parent = filepath.Dir(parent) {
+		if _, err := os.Stat(filepath.Join(parent, "go.mod")); err == nil {
+			root = parent
+			break
+		} else if filepath.Dir(parent) == parent {
+			break
+		}
+	}
+	moduleRoots.Store(dir, root)
+	return root
+}
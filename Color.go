@@ -12,6 +12,8 @@ var (
 	cyan          = ansi.ColorCode("cyan")
 	gray          = ansi.LightBlack
 	grayUnderline = ansi.ColorCode("180+u")
+	redInverse    = ansi.ColorCode("red+i")
+	greenInverse  = ansi.ColorCode("green+i")
 	reset         = ansi.ColorCode("reset")
 )
 
@@ -0,0 +1,191 @@
+package sugar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Compare performs a deep reflection over two interfaces and logs every difference that it finds, grouped by where it
+// found them, underneath a count of how many there are. It returns true if the two interfaces match eachother.
+func (log Log) Compare(a, b interface{}, opts ...CompareOption) bool {
+	c := diff(a, b, opts)
+	if len(c.ignored) > 0 {
+		ignoredLogger := NewLogger()
+		for _, ignored := range c.ignored {
+			ignoredLogger.Log(rootName(a) + ignored)
+		}
+		log("ignored %d unexported fields", len(c.ignored))
+		log(ignoredLogger)
+	}
+	if len(c.differences) == 0 {
+		return true
+	}
+	render(log, rootName(a), c)
+	return false
+}
+
+// CompareJSON decodes two JSON documents and compares them the same way that Compare does, so that the order of keys
+// and whitespace don't matter. Documents can be passed in as a []byte, a string or a json.RawMessage, and any other
+// value is encoded to JSON first. Differences are logged by their JSON pointer, eg. `/items/2/name`, and options like
+// IgnorePaths and IgnoreOrder accept JSON pointers too. Numbers are compared by their exact value, so 1.0 equals 1 but
+// large ids that a float64 can't tell apart don't equal eachother, unless FloatTolerance is passed in.
+func (log Log) CompareJSON(expected, actual interface{}, opts ...CompareOption) bool {
+	a, err := decodeJSON(expected)
+	if err != nil {
+		log("could not decode the expected JSON: %s", err)
+		return false
+	}
+	b, err := decodeJSON(actual)
+	if err != nil {
+		log("could not decode the actual JSON: %s", err)
+		return false
+	}
+	c := diff(a, b, append([]CompareOption{usePointers}, opts...))
+	if len(c.differences) == 0 {
+		return true
+	}
+	render(log, "", c)
+	return false
+}
+
+// CompareSubset compares only the values that are set in expected against actual, so that values like generated IDs
+// and timestamps can be left out of expected. Zero values in expected are skipped, and keys and slice elements that
+// are only in actual are allowed. It logs every value that it checked as well as every difference that it found, and
+// accepts the same options as Compare.
+func (log Log) CompareSubset(expected, actual interface{}, opts ...CompareOption) bool {
+	c := diff(expected, actual, append([]CompareOption{subset}, opts...))
+	root := rootName(expected)
+	checkedLogger := NewLogger()
+	for _, checked := range c.checked {
+		checkedLogger.Log(root + checked)
+	}
+	if len(c.checked) == 1 {
+		log("checked 1 value")
+	} else {
+		log("checked %d values", len(c.checked))
+	}
+	log(checkedLogger)
+	if len(c.differences) == 0 {
+		return true
+	}
+	render(log, root, c)
+	return false
+}
+
+// subset makes a comparer only check the values that are set in a
+func subset(c *comparer) {
+	c.isSubset = true
+	c.isIgnoringZeroExpected = true
+}
+
+// usePointers makes a comparer print the paths of differences as JSON pointers
+func usePointers(c *comparer) {
+	c.isUsingPointers = true
+}
+
+// decodeJSON returns the value of a JSON document, or the value that anything else encodes to
+func decodeJSON(v interface{}) (interface{}, error) {
+	var data []byte
+	switch document := v.(type) {
+	case []byte:
+		data = document
+	case json.RawMessage:
+		data = document
+	case string:
+		data = []byte(document)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	// decode numbers as json.Number, so that they are compared exactly instead of as float64s, which can't tell large
+	// ids like 9007199254740993 and 9007199254740992 apart
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result interface{}
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	} else if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid character after the top-level value")
+	}
+	return result, nil
+}
+
+// rootName returns the name of the type of a value that is being compared, which prefixes the paths of differences
+func rootName(a interface{}) string {
+	t := reflect.TypeOf(a)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// countDifferences prints how many differences there are
+func countDifferences(n int) string {
+	if n == 1 {
+		return "1 difference"
+	}
+	return fmt.Sprintf("%d differences", n)
+}
+
+// render logs a count of the differences, followed by each difference underneath the path where it was found. Long
+// strings and []byte are rendered as a unified diff. Other styles are rendered by their own funcs.
+func render(log Log, root string, c *comparer) {
+	differences := c.differences
+	style := c.style
+	if style == DefaultStyle {
+		style = defaultStyle
+	}
+	switch style {
+	case SideBySideStyle:
+		renderSideBySide(log, root, differences)
+		return
+	case CompactStyle:
+		renderCompact(log, root, differences)
+		return
+	}
+
+	log(countDifferences(len(differences)))
+	nestedLogger := NewLogger()
+	for _, d := range differences {
+		at := root + d.Path
+		if at == "" {
+			at = "the root"
+		}
+		differenceLogger := NewLogger()
+		switch d.Kind {
+		case Added:
+			nestedLogger.Log("%s was not expected", at)
+			differenceLogger.Log("found   : %v.(%T)", d.Found, d.Found)
+		case Removed:
+			nestedLogger.Log("%s is missing", at)
+			differenceLogger.Log("expected: %v.(%T)", d.Expected, d.Expected)
+		case TypeMismatch:
+			nestedLogger.Log("%s has a different type", at)
+			differenceLogger.Log("expected: %v.(%T)", d.Expected, d.Expected)
+			differenceLogger.Log("found   : %v.(%T)", d.Found, d.Found)
+		default:
+			nestedLogger.Log(at)
+			if a, b, ok := textLines(d.Expected, d.Found); ok {
+				for _, hunk := range unifiedDiff(a, b, c.contextLines, c.isHighlightingCharacters) {
+					differenceLogger.Log(hunk)
+				}
+				break
+			}
+			differenceLogger.Log("expected: %v.(%T)", d.Expected, d.Expected)
+			differenceLogger.Log("found   : %v.(%T)", d.Found, d.Found)
+		}
+		if d.Comparer != "" {
+			differenceLogger.Log("compared by %s", d.Comparer)
+		}
+		nestedLogger.Log(differenceLogger)
+	}
+	log(nestedLogger)
+}
@@ -3,76 +3,427 @@ package sugar
 import (
 	"encoding"
 	"fmt"
+	"math"
 	"reflect"
+	"strings"
+	"unsafe"
 )
 
-// Copy copies a into b
-func Copy(a, b interface{}) error {
+// Cloner can be implemented by types that need to take over their own copying. Copy uses the value returned by Clone
+// instead of copying the type field by field.
+type Cloner interface {
+	Clone() interface{}
+}
+
+// Copy copies a into b. Pointers, maps and slices that are shared by more than one field in a are shared by the same
+// fields in b, so data structures with back-references and cycles can be copied too. Chans and funcs are shared by
+// reference, unless the RejectChansAndFuncs option is passed in. Unexported fields are skipped, unless the
+// CopyUnexported option is passed in.
+func Copy(a, b interface{}, opts ...CopyOption) error {
+	aValue := reflect.ValueOf(a)
+	bValue := reflect.ValueOf(b)
+	return newCopier(aValue, opts).start(aValue, bValue)
+}
+
+// CopyMapped copies a into b, even if a and b are different types. Struct fields are matched by name, or by the name
+// in their `sugar:"name"` tag, rather than by their order, and values are converted between compatible kinds, eg. int
+// widths, string and []byte, or pointers and values like time.Time and *time.Time. It returns the paths of the fields
+// in a that could not be copied into b, including numbers that would overflow b or lose their fraction. Fields that are
+// tagged `sugar:"-"` or `sugar:"ignore"`, which Diff skips, aren't mapped at all, and neither are names that more than
+// one field of b maps to.
+func CopyMapped(a, b interface{}, opts ...CopyOption) ([]string, error) {
 	aValue := reflect.ValueOf(a)
 	bValue := reflect.ValueOf(b)
-	return copy(aValue, bValue)
+	c := newCopier(aValue, opts)
+	c.isMapped = true
+	err := c.start(aValue, bValue)
+	return c.unmapped, err
 }
 
-func copy(a, b reflect.Value) error {
-	// make sure we're always copying the same type of thing
-	if a.Kind() != b.Kind() {
-		return fmt.Errorf("tried to copy incompatible types")
+// CopyOption changes the way that Copy and CopyMapped copy values
+type CopyOption func(*copier)
+
+// RejectChansAndFuncs makes Copy return an error instead of sharing chans and funcs between a and b
+func RejectChansAndFuncs() CopyOption {
+	return func(c *copier) {
+		c.isRejectingReferences = true
 	}
+}
 
-	// marshal and unmarshal data via the binary marshaler/unmarshaler
-	if a.CanInterface() && b.CanInterface() {
-		// make sure we're not copying to or from a nil value
-		if a.Kind() == reflect.Ptr && a.IsNil() {
+// SkipFields skips every struct field with one of the names passed in, no matter how deeply it is nested
+func SkipFields(names ...string) CopyOption {
+	return func(c *copier) {
+		if c.skippedNames == nil {
+			c.skippedNames = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.skippedNames[name] = true
+		}
+	}
+}
+
+// SkipPaths skips the values at the paths passed in. Paths are relative to the value being copied, and `*` matches any
+// field, index or key, eg. `Items[*].ID`.
+func SkipPaths(paths ...string) CopyOption {
+	return func(c *copier) {
+		for _, p := range paths {
+			c.skippedPaths = append(c.skippedPaths, parsePattern(p))
+		}
+	}
+}
+
+// SkipTagged skips every struct field that has the value passed in in its tag, eg. SkipTagged("json", "-")
+func SkipTagged(key, value string) CopyOption {
+	return func(c *copier) {
+		c.skippedTags = append(c.skippedTags, [2]string{key, value})
+	}
+}
+
+// MaxDepth copies values that are nested deeper than depth fields, indexes or keys by assignment, so they are shared
+// with a rather than copied
+func MaxDepth(depth int) CopyOption {
+	return func(c *copier) {
+		c.maxDepth = depth
+	}
+}
+
+// CopyUnexported copies unexported fields as well as exported ones
+func CopyUnexported() CopyOption {
+	return func(c *copier) {
+		c.isCopyingUnexported = true
+	}
+}
+
+// copier holds the state of a single call to Copy
+type copier struct {
+	// visited maps the pointers, maps and slices in a that have already been copied to their copies in b
+	visited map[visit]reflect.Value
+
+	// path is the path of the value that is currently being copied
+	path path
+
+	// isMapped allows a and b to be different types, see CopyMapped
+	isMapped bool
+
+	// unmapped holds the paths of the values in a that could not be copied into b
+	unmapped []string
+
+	// isRejectingReferences returns an error for chans and funcs instead of sharing them, see RejectChansAndFuncs
+	isRejectingReferences bool
+
+	// skippedNames, skippedPaths and skippedTags are the fields that are not copied, see SkipFields, SkipPaths and
+	// SkipTagged
+	skippedNames map[string]bool
+	skippedPaths []pattern
+	skippedTags  [][2]string
+
+	// maxDepth is the depth below which values are shared rather than copied, or -1 for no limit, see MaxDepth
+	maxDepth int
+
+	// isCopyingUnexported copies unexported fields, see CopyUnexported
+	isCopyingUnexported bool
+
+	// isAppendingSlices and isMergingMaps change the way that Merge combines slices and maps, see AppendSlices and
+	// MergeMaps
+	isAppendingSlices bool
+	isMergingMaps     bool
+
+	// changed holds the paths of the values in b that were changed by Merge
+	changed []string
+
+	// isUncached compiles a new plan every time one is needed instead of using the cached plans
+	isUncached bool
+
+	// root is the name of the type that is being copied, it prefixes every path
+	root string
+}
+
+// visit identifies a pointer, map or slice that has already been copied into a value of type to
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	to  reflect.Type
+	len int
+}
+
+func newCopier(a reflect.Value, opts []CopyOption) *copier {
+	c := copier{
+		visited:  make(map[visit]reflect.Value),
+		maxDepth: -1,
+	}
+	if a.IsValid() {
+		c.root = typeName(a.Type())
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// start copies a into the value that b points to
+func (c *copier) start(a, b reflect.Value) error {
+	if !b.IsValid() || b.Kind() != reflect.Ptr || b.IsNil() {
+		return c.errorf("tried to copy into %v, which is not a pointer", b)
+	} else if a.IsValid() && a.Kind() != reflect.Ptr && !c.isMapped {
+		// copy a value into the value that b points to
+		return c.copy(a, b.Elem())
+	}
+	return c.copy(a, b)
+}
+
+// copyAt copies a into b, where a and b are found at step s from the value that is currently being copied
+func (c *copier) copyAt(s step, a, b reflect.Value) error {
+	c.path = append(c.path, s)
+	err := c.copy(a, b)
+	c.path = c.path[:len(c.path)-1]
+	return err
+}
+
+// at returns the path of the value that is currently being copied, prefixed by the name of the root type
+func (c *copier) at() string {
+	return c.root + c.path.String()
+}
+
+// errorf returns an error that is prefixed by the path of the value that could not be copied
+func (c *copier) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", c.at(), fmt.Sprintf(format, args...))
+}
+
+// isSkipping returns true if any fields are skipped, so structs can't be copied by assignment
+func (c *copier) isSkipping() bool {
+	return len(c.skippedNames) > 0 || len(c.skippedPaths) > 0 || len(c.skippedTags) > 0
+}
+
+// isSkipped returns true if the struct field at the end of the current path should not be copied
+func (c *copier) isSkipped(f *fieldPlan) bool {
+	if c.skippedNames[f.name] {
+		return true
+	}
+	for _, pt := range c.skippedPaths {
+		if pt.match(c.path) {
+			return true
+		}
+	}
+	for _, tag := range c.skippedTags {
+		for _, value := range strings.Split(f.tag.Get(tag[0]), ",") {
+			if value == tag[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// field returns the field f of struct v, or false if it can't be copied
+func (c *copier) field(v reflect.Value, f *fieldPlan) (reflect.Value, bool) {
+	field := v.Field(f.index)
+	if f.isExported {
+		return field, true
+	} else if !c.isCopyingUnexported || !v.CanAddr() {
+		return field, false
+	}
+	return exported(field), true
+}
+
+// addressable returns v if it can be addressed, or an addressable copy of v if it can't, so that its unexported fields
+// can be reached
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	a := reflect.New(v.Type()).Elem()
+	a.Set(v)
+	return a
+}
+
+// exported returns a field of an addressable struct in a way that lets it be set and interfaced, even if the field is
+// unexported
+func exported(field reflect.Value) reflect.Value {
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// clone sets b to the value returned by a's Clone method, and returns false if the clone can't be assigned to b
+func clone(a, b reflect.Value) bool {
+	if !a.CanInterface() {
+		return false
+	}
+	v := reflect.ValueOf(a.Interface().(Cloner).Clone())
+	switch {
+	case !v.IsValid():
+		return false
+	case v.Type().AssignableTo(b.Type()) && b.CanSet():
+		b.Set(v)
+	case v.Type() == b.Type() && b.Kind() == reflect.Ptr && !v.IsNil() && !b.IsNil():
+		b.Elem().Set(v.Elem())
+	case b.Kind() == reflect.Ptr && !b.IsNil() && v.Type().AssignableTo(b.Type().Elem()):
+		b.Elem().Set(v)
+	case v.Kind() == reflect.Ptr && !v.IsNil() && v.Type().Elem().AssignableTo(b.Type()) && b.CanSet():
+		b.Set(v.Elem())
+	default:
+		return false
+	}
+	return true
+}
+
+// seen sets b to the copy of a if a has already been copied, and returns true if it did
+func (c *copier) seen(a, b reflect.Value) bool {
+	v, ok := c.visited[c.key(a, b)]
+	if ok && b.CanSet() {
+		b.Set(v)
+	}
+	return ok
+}
+
+// key returns the key that the copy of a into b is stored under in visited
+func (c *copier) key(a, b reflect.Value) visit {
+	if a.Kind() == reflect.Slice {
+		return visit{a.Pointer(), a.Type(), b.Type(), a.Len()}
+	}
+	return visit{ptr: a.Pointer(), typ: a.Type(), to: b.Type()}
+}
+
+func (c *copier) copy(a, b reflect.Value) error {
+	// there is nothing to copy out of an untyped nil
+	if !a.IsValid() {
+		return nil
+	}
+
+	// make sure we're always copying the same type of thing, unless we've been asked to map one type onto another
+	if a.Type() != b.Type() && c.isMapped {
+		return c.convert(a, b)
+	} else if a.Kind() != b.Kind() {
+		return c.errorf("tried to copy incompatible types %s and %s", a.Type(), b.Type())
+	}
+
+	// share everything below the max depth
+	if c.maxDepth >= 0 && len(c.path) >= c.maxDepth && b.CanSet() {
+		b.Set(a)
+		return nil
+	}
+
+	// share pointers that we've already copied and allocate the ones we haven't
+	if a.Kind() == reflect.Ptr {
+		if a.IsNil() || c.seen(a, b) {
 			return nil
-		} else if b.Kind() == reflect.Ptr && b.IsNil() && b.CanSet() {
+		} else if b.IsNil() && b.CanSet() {
 			b.Set(reflect.New(b.Type().Elem()))
 		}
+		c.visited[c.key(a, b)] = b
+	}
+
+	pl := c.plan(a.Type())
+
+	// let types that know how to copy themselves do it
+	if pl.isCloner && clone(a, b) {
+		return nil
+	}
 
-		aMarshaler, aOK := a.Interface().(encoding.BinaryMarshaler)
+	// values that don't refer to anything else can just be assigned, unless they have fields in them that are skipped
+	if pl.isValue && b.CanSet() && (!c.isSkipping() || a.Kind() != reflect.Struct && a.Kind() != reflect.Array) {
+		b.Set(a)
+		return nil
+	}
+
+	// marshal and unmarshal data via the binary marshaler/unmarshaler
+	if pl.isMarshaler && (pl.isUnmarshaler || pl.isPtrUnmarshaler && b.CanAddr()) && a.CanInterface() && b.CanInterface() {
+		aMarshaler := a.Interface().(encoding.BinaryMarshaler)
 		bUnmarshaler, bOK := b.Interface().(encoding.BinaryUnmarshaler)
-		if aOK && bOK {
-			if bs, err := aMarshaler.MarshalBinary(); err == nil {
-				if err := bUnmarshaler.UnmarshalBinary(bs); err == nil {
-					return nil
-				}
+		if !bOK {
+			bUnmarshaler = b.Addr().Interface().(encoding.BinaryUnmarshaler)
+		}
+		if bs, err := aMarshaler.MarshalBinary(); err == nil {
+			if err := bUnmarshaler.UnmarshalBinary(bs); err == nil {
+				return nil
 			}
 		}
 	}
 
 	switch a.Kind() {
 	case reflect.Ptr:
+		return c.copy(a.Elem(), b.Elem())
+	case reflect.Slice:
 		if a.IsNil() {
+			b.Set(reflect.Zero(b.Type()))
+			return nil
+		} else if c.seen(a, b) {
 			return nil
-		} else if b.IsNil() && b.CanSet() {
-			b.Set(reflect.New(b.Type().Elem()))
 		}
-		return copy(a.Elem(), b.Elem())
-	case reflect.Slice:
 		b.Set(reflect.MakeSlice(a.Type(), a.Len(), a.Cap()))
+		c.visited[c.key(a, b)] = b
 		for i, l := 0, a.Len(); i < l; i++ {
-			if err := copy(a.Index(i), b.Index(i)); err != nil {
+			if err := c.copyAt(indexStep(i), a.Index(i), b.Index(i)); err != nil {
 				return err
 			}
 		}
 	case reflect.Array:
-		b.Set(reflect.New(reflect.ArrayOf(a.Len(), a.Type())))
 		for i, l := 0, a.Len(); i < l; i++ {
-			if err := copy(a.Index(i), b.Index(i)); err != nil {
+			if err := c.copyAt(indexStep(i), a.Index(i), b.Index(i)); err != nil {
 				return err
 			}
 		}
 	case reflect.Struct:
-		for i, l := 0, a.Type().NumField(); i < l; i++ {
-			copy(a.Field(i), b.Field(i))
+		// unexported fields can only be copied out of a struct that can be addressed
+		if c.isCopyingUnexported {
+			a = addressable(a)
+		}
+		for i := range pl.fields {
+			f := &pl.fields[i]
+			aField, aOK := c.field(a, f)
+			bField, bOK := c.field(b, f)
+			if !aOK || !bOK {
+				continue
+			}
+			c.path = append(c.path, fieldStep(f.name))
+			var err error
+			if !c.isSkipped(f) {
+				err = c.copy(aField, bField)
+			}
+			c.path = c.path[:len(c.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		// copy the value inside of the interface by its concrete type
+		if a.IsNil() {
+			b.Set(reflect.Zero(b.Type()))
+			return nil
 		}
+		value := reflect.New(a.Elem().Type()).Elem()
+		if err := c.copy(a.Elem(), value); err != nil {
+			return err
+		}
+		b.Set(value)
 	case reflect.Map:
-		for _, key := range a.MapKeys() {
-			b.SetMapIndex(key, a.MapIndex(key))
+		if a.IsNil() {
+			b.Set(reflect.Zero(b.Type()))
+			return nil
+		} else if c.seen(a, b) {
+			return nil
 		}
+		// always make a new map, so that keys that were already in b don't survive the copy
+		b.Set(reflect.MakeMapWithSize(b.Type(), a.Len()))
+		c.visited[c.key(a, b)] = b
+		for iter := a.MapRange(); iter.Next(); {
+			// copy the values in the map too, so that they can refer back to things we've already copied
+			value := reflect.New(b.Type().Elem()).Elem()
+			if err := c.copyAt(keyStep(iter.Key()), iter.Value(), value); err != nil {
+				return err
+			}
+			b.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.Chan, reflect.Func:
+		if c.isRejectingReferences && !a.IsNil() {
+			return c.errorf("tried to copy a %s", a.Type())
+		}
+		b.Set(a)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128,
 		reflect.String,
-		reflect.Bool:
+		reflect.Bool,
+		reflect.UnsafePointer:
 		if b.CanSet() {
 			b.Set(a)
 		}
@@ -80,3 +431,179 @@ func copy(a, b reflect.Value) error {
 
 	return nil
 }
+
+// convert copies a into b when they are different types
+func (c *copier) convert(a, b reflect.Value) error {
+	switch {
+	case a.Kind() == reflect.Ptr && b.Kind() != reflect.Ptr:
+		if a.IsNil() {
+			return nil
+		}
+		return c.copy(a.Elem(), b)
+	case b.Kind() == reflect.Ptr:
+		if a.Kind() == reflect.Ptr {
+			if a.IsNil() || c.seen(a, b) {
+				return nil
+			}
+			c.visited[c.key(a, b)] = b
+			a = a.Elem()
+		}
+		if b.IsNil() {
+			if !b.CanSet() {
+				c.unmapped = append(c.unmapped, c.at())
+				return nil
+			}
+			b.Set(reflect.New(b.Type().Elem()))
+		}
+		return c.copy(a, b.Elem())
+	case a.Kind() == reflect.Interface:
+		if a.IsNil() {
+			return nil
+		}
+		return c.copy(a.Elem(), b)
+	case b.Kind() == reflect.Interface && a.Type().Implements(b.Type()):
+		value := reflect.New(a.Type()).Elem()
+		if err := c.copy(a, value); err != nil {
+			return err
+		}
+		b.Set(value)
+	case a.Kind() == reflect.Struct && b.Kind() == reflect.Struct:
+		return c.convertFields(a, b)
+	case a.Kind() == reflect.Slice && b.Kind() == reflect.Slice && b.CanSet():
+		if a.IsNil() {
+			b.Set(reflect.Zero(b.Type()))
+			return nil
+		} else if c.seen(a, b) {
+			return nil
+		}
+		b.Set(reflect.MakeSlice(b.Type(), a.Len(), a.Len()))
+		c.visited[c.key(a, b)] = b
+		for i, l := 0, a.Len(); i < l; i++ {
+			if err := c.copyAt(indexStep(i), a.Index(i), b.Index(i)); err != nil {
+				return err
+			}
+		}
+	case a.Kind() == reflect.Map && b.Kind() == reflect.Map && b.CanSet():
+		if a.IsNil() {
+			b.Set(reflect.Zero(b.Type()))
+			return nil
+		} else if c.seen(a, b) {
+			return nil
+		}
+		b.Set(reflect.MakeMap(b.Type()))
+		c.visited[c.key(a, b)] = b
+		for iter := a.MapRange(); iter.Next(); {
+			key := reflect.New(b.Type().Key()).Elem()
+			if err := c.copyAt(keyStep(iter.Key()), iter.Key(), key); err != nil {
+				return err
+			}
+			value := reflect.New(b.Type().Elem()).Elem()
+			if err := c.copyAt(keyStep(iter.Key()), iter.Value(), value); err != nil {
+				return err
+			}
+			b.SetMapIndex(key, value)
+		}
+	case isConvertible(a.Type(), b.Type()) && b.CanSet() && fits(a, b.Type()):
+		b.Set(a.Convert(b.Type()))
+	default:
+		c.unmapped = append(c.unmapped, c.at())
+	}
+	return nil
+}
+
+// convertFields copies the fields of struct a into the fields of struct b that have the same name
+func (c *copier) convertFields(a, b reflect.Value) error {
+	aPlan, bPlan := c.plan(a.Type()), c.plan(b.Type())
+	for i := range aPlan.fields {
+		f := &aPlan.fields[i]
+		if !f.isExported || f.isIgnored {
+			continue
+		}
+		c.path = append(c.path, fieldStep(f.name))
+		var err error
+		if !c.isSkipped(f) {
+			if j, ok := bPlan.fieldsByName[f.mappedName]; ok {
+				err = c.copy(a.Field(f.index), b.Field(j))
+			} else {
+				c.unmapped = append(c.unmapped, c.at())
+			}
+		}
+		c.path = c.path[:len(c.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isConvertible returns true if a value of type a can be converted into a value of type b without changing its meaning
+func isConvertible(a, b reflect.Type) bool {
+	if !a.ConvertibleTo(b) {
+		return false
+	}
+	switch {
+	case a.Kind() == b.Kind():
+		return true
+	case isNumber(a.Kind()) && isNumber(b.Kind()):
+		return true
+	case a.Kind() == reflect.String:
+		return b.Kind() == reflect.Slice
+	case b.Kind() == reflect.String:
+		return a.Kind() == reflect.Slice
+	}
+	return false
+}
+
+// fits returns false if converting the number a into type t would overflow or lose the fraction of a float, and true
+// for everything else
+func fits(a reflect.Value, t reflect.Type) bool {
+	b := reflect.Zero(t)
+	switch {
+	case !isNumber(a.Kind()) || !isNumber(t.Kind()):
+		return true
+	case isInt(a.Kind()) && isInt(t.Kind()):
+		return !b.OverflowInt(a.Int())
+	case isInt(a.Kind()) && isUint(t.Kind()):
+		return a.Int() >= 0 && !b.OverflowUint(uint64(a.Int()))
+	case isUint(a.Kind()) && isInt(t.Kind()):
+		return a.Uint() <= math.MaxInt64 && !b.OverflowInt(int64(a.Uint()))
+	case isUint(a.Kind()) && isUint(t.Kind()):
+		return !b.OverflowUint(a.Uint())
+	case isFloat(a.Kind()) && isFloat(t.Kind()):
+		return !b.OverflowFloat(a.Float())
+	case isFloat(a.Kind()) && isInt(t.Kind()):
+		f := a.Float()
+		return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && !b.OverflowInt(int64(f))
+	case isFloat(a.Kind()) && isUint(t.Kind()):
+		f := a.Float()
+		return f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !b.OverflowUint(uint64(f))
+	}
+	// ints and uints always fit into floats, even if they lose some precision
+	return true
+}
+
+// isInt returns true for all of the int kinds
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// isUint returns true for all of the uint kinds
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// isFloat returns true for all of the float kinds
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// isNumber returns true for all of the int, uint and float kinds
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package sugar
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// plan is everything that Copy needs to know about a type. Working it out means walking the type's reflection
+// metadata, so a plan is compiled once per type and then cached for every copy that follows.
+type plan struct {
+	// isCloner is true if the type implements Cloner
+	isCloner bool
+
+	// isMarshaler is true if the type implements encoding.BinaryMarshaler, isUnmarshaler is true if it implements
+	// encoding.BinaryUnmarshaler and isPtrUnmarshaler is true if a pointer to it does
+	isMarshaler      bool
+	isUnmarshaler    bool
+	isPtrUnmarshaler bool
+
+	// isValue is true for types that don't refer to anything else, so they can be copied by assignment
+	isValue bool
+
+	// isOpaque is true for structs that are copied as a whole rather than field by field, like time.Time
+	isOpaque bool
+
+	// fields are the fields of a struct, in order
+	fields []fieldPlan
+
+	// fieldsByName maps the mapped names of the exported fields to their indexes, see CopyMapped. Names that more
+	// than one field maps to are left out, so that neither of them can be copied into by mistake.
+	fieldsByName map[string]int
+}
+
+// fieldPlan is everything that Copy needs to know about a struct field
+type fieldPlan struct {
+	index      int
+	name       string
+	mappedName string
+	tag        reflect.StructTag
+	isExported bool
+	// isIgnored is true for fields that are tagged `sugar:"-"` or `sugar:"ignore"`, which CopyMapped doesn't map
+	isIgnored bool
+}
+
+var (
+	// plans caches the plan of every type that has been copied
+	plans sync.Map
+
+	clonerType            = reflect.TypeOf((*Cloner)(nil)).Elem()
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// plan returns the plan for type t
+func (c *copier) plan(t reflect.Type) *plan {
+	if c.isUncached {
+		return compile(t)
+	} else if p, ok := plans.Load(t); ok {
+		return p.(*plan)
+	}
+	p, _ := plans.LoadOrStore(t, compile(t))
+	return p.(*plan)
+}
+
+// compile works out the plan for type t
+func compile(t reflect.Type) *plan {
+	ptr := reflect.PtrTo(t)
+	p := plan{
+		isCloner:         t.Implements(clonerType),
+		isMarshaler:      t.Implements(binaryMarshalerType),
+		isUnmarshaler:    t.Implements(binaryUnmarshalerType),
+		isPtrUnmarshaler: ptr.Implements(binaryUnmarshalerType),
+		isValue:          isValue(t),
+	}
+	p.isOpaque = p.isCloner || ptr.Implements(clonerType) || p.isMarshaler || ptr.Implements(binaryMarshalerType)
+	if t.Kind() == reflect.Struct {
+		p.fieldsByName = make(map[string]int)
+		ambiguous := make(map[string]bool)
+		for i, l := 0, t.NumField(); i < l; i++ {
+			field := t.Field(i)
+			f := fieldPlan{
+				index:      i,
+				name:       field.Name,
+				mappedName: field.Name,
+				tag:        field.Tag,
+				isExported: field.PkgPath == "",
+				isIgnored:  isIgnoredTag(field.Tag),
+			}
+			if name := strings.Split(field.Tag.Get("sugar"), ",")[0]; name != "" && !f.isIgnored {
+				f.mappedName = name
+			}
+			if _, isTaken := p.fieldsByName[f.mappedName]; !f.isExported || f.isIgnored {
+				// unexported and ignored fields aren't mapped
+			} else if isTaken || ambiguous[f.mappedName] {
+				delete(p.fieldsByName, f.mappedName)
+				ambiguous[f.mappedName] = true
+			} else {
+				p.fieldsByName[f.mappedName] = i
+			}
+			p.fields = append(p.fields, f)
+		}
+	}
+	return &p
+}
+
+// isIgnoredTag returns true for fields that are tagged `sugar:"-"` or `sugar:"ignore"`, which Diff skips and
+// CopyMapped doesn't map
+func isIgnoredTag(tag reflect.StructTag) bool {
+	name := strings.Split(tag.Get("sugar"), ",")[0]
+	return name == "-" || name == "ignore"
+}
+
+// isValue returns true for types that can be copied by assignment, because they don't contain any pointers, slices,
+// maps, interfaces, chans, funcs or unexported fields. time.Time is also treated as a value.
+func isValue(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128,
+		reflect.String,
+		reflect.Bool:
+		return !t.Implements(clonerType)
+	case reflect.Array:
+		return isValue(t.Elem()) && !t.Implements(clonerType)
+	case reflect.Struct:
+		if t == timeType {
+			return true
+		} else if t.Implements(clonerType) || t.Implements(binaryMarshalerType) {
+			return false
+		}
+		for i, l := 0, t.NumField(); i < l; i++ {
+			if field := t.Field(i); field.PkgPath != "" || !isValue(field.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
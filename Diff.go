@@ -0,0 +1,752 @@
+package sugar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jsonNumberType is the type of the numbers that CompareJSON decodes
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// Difference is a single difference between two values, found by Diff
+type Difference struct {
+	// Path is where the difference was found, relative to the values being compared, eg. `.Items[2].Name`
+	Path string
+
+	// Kind is the kind of change that was found
+	Kind ChangeKind
+
+	// Expected and Found are the values at Path in a and b. Expected is nil if a value was added, and Found is nil if
+	// a value was removed.
+	Expected interface{}
+	Found    interface{}
+
+	// Comparer is the name of the Equal method or the registered comparer that found the difference, if one did
+	Comparer string
+}
+
+// String prints the difference on a single line
+func (d Difference) String() string {
+	switch d.Kind {
+	case Added:
+		return fmt.Sprintf("%s: %s %+v", d.Path, d.Kind, d.Found)
+	case Removed:
+		return fmt.Sprintf("%s: %s %+v", d.Path, d.Kind, d.Expected)
+	}
+	if d.Comparer != "" {
+		return fmt.Sprintf("%s: %s by %s, expected %+v.(%T), found %+v.(%T)", d.Path, d.Kind, d.Comparer, d.Expected, d.Expected, d.Found, d.Found)
+	}
+	return fmt.Sprintf("%s: %s, expected %+v.(%T), found %+v.(%T)", d.Path, d.Kind, d.Expected, d.Expected, d.Found, d.Found)
+}
+
+// ChangeKind is the kind of change that a Difference describes
+type ChangeKind int
+
+const (
+	// Changed means that the value in b is different from the value in a
+	Changed ChangeKind = iota
+
+	// Added means that b has a slice element or map key that a doesn't have
+	Added
+
+	// Removed means that a has a slice element or map key that b doesn't have
+	Removed
+
+	// TypeMismatch means that the value in b is a different type than the value in a
+	TypeMismatch
+)
+
+// String returns the name of the kind of change
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case TypeMismatch:
+		return "type mismatch"
+	}
+	return "changed"
+}
+
+// CompareOption changes the way that Diff and Compare compare values
+type CompareOption func(*comparer)
+
+// StopAtFirstDifference makes Diff stop at the first difference it finds instead of finding every one of them
+func StopAtFirstDifference() CompareOption {
+	return func(c *comparer) {
+		c.isStoppingAtFirst = true
+	}
+}
+
+// IgnoreUnexported skips unexported struct fields instead of comparing them. Compare notes which fields it skipped.
+func IgnoreUnexported() CompareOption {
+	return func(c *comparer) {
+		c.isIgnoringUnexported = true
+	}
+}
+
+// OmitJSONIgnored skips struct fields that are tagged `json:"-"`
+func OmitJSONIgnored() CompareOption {
+	return func(c *comparer) {
+		c.isOmittingJSONIgnored = true
+	}
+}
+
+// IgnorePaths skips the values at the paths passed in. Paths are relative to the values being compared, and `*`
+// matches any field, index or key, eg. IgnorePaths("UpdatedAt", "Items[*].ID"). Paths can also be written as JSON
+// pointers, eg. IgnorePaths("/items/*/id").
+func IgnorePaths(paths ...string) CompareOption {
+	return func(c *comparer) {
+		for _, p := range paths {
+			c.ignoredPaths = append(c.ignoredPaths, parsePattern(p))
+		}
+	}
+}
+
+// IgnoreTypes skips every value that is the same type as one of the values passed in, eg. IgnoreTypes(time.Time{})
+func IgnoreTypes(values ...interface{}) CompareOption {
+	return func(c *comparer) {
+		if c.ignoredTypes == nil {
+			c.ignoredTypes = make(map[reflect.Type]bool)
+		}
+		for _, value := range values {
+			c.ignoredTypes[reflect.TypeOf(value)] = true
+		}
+	}
+}
+
+// FloatTolerance treats floats as equal if the difference between them is no more than abs, or no more than rel times
+// the larger of the two
+func FloatTolerance(abs, rel float64) CompareOption {
+	return func(c *comparer) {
+		c.floatAbs, c.floatRel = abs, rel
+	}
+}
+
+// TimeTolerance treats times as equal if they are no more than d apart. The default tolerance is one second.
+func TimeTolerance(d time.Duration) CompareOption {
+	return func(c *comparer) {
+		c.timeTolerance = d
+	}
+}
+
+// IgnoreZeroExpected skips every value in a that is zero, so that only the values that a sets are compared against b
+func IgnoreZeroExpected() CompareOption {
+	return func(c *comparer) {
+		c.isIgnoringZeroExpected = true
+	}
+}
+
+// IgnoreOrder compares slices as if they were unordered, matching each element in a against an equal element in b
+// wherever it is. Elements without an equal match are reported against the element they are closest to, and the rest
+// are reported as missing or unexpected. If paths are passed in then only the slices at those paths are unordered,
+// eg. IgnoreOrder("Items", "Items[*].Sizes"), otherwise every slice is.
+func IgnoreOrder(paths ...string) CompareOption {
+	return func(c *comparer) {
+		if len(paths) == 0 {
+			c.isIgnoringOrder = true
+		}
+		for _, p := range paths {
+			c.unorderedPaths = append(c.unorderedPaths, parsePattern(p))
+		}
+	}
+}
+
+// SortSlicesBy sorts slices of a type before comparing them, by the key that is returned for each of their elements.
+// key must be a func that takes the element type and returns an int, uint, float or string, eg.
+// SortSlicesBy(func(i Item) string { return i.Name }). It panics if key is any other kind of value.
+func SortSlicesBy(key interface{}) CompareOption {
+	keyValue := reflect.ValueOf(key)
+	if keyValue.Kind() != reflect.Func || keyValue.Type().NumIn() != 1 || keyValue.Type().NumOut() != 1 {
+		panic(fmt.Sprintf("sugar: SortSlicesBy needs a func that takes a value and returns a key, not %T", key))
+	}
+	return func(c *comparer) {
+		if c.sortKeys == nil {
+			c.sortKeys = make(map[reflect.Type]reflect.Value)
+		}
+		c.sortKeys[keyValue.Type().In(0)] = keyValue
+	}
+}
+
+// DistinguishNil reports nil slices and maps as different from empty ones, which are treated as equal by default
+func DistinguishNil() CompareOption {
+	return func(c *comparer) {
+		c.isDistinguishingNil = true
+	}
+}
+
+// MaxCompareDepth stops comparing values that are nested more than depth fields, indexes or keys deep, treating
+// them as equal. Cycles are always detected, so this is only needed for structures that are extremely deep.
+func MaxCompareDepth(depth int) CompareOption {
+	return func(c *comparer) {
+		c.maxDepth = depth
+	}
+}
+
+// DiffContext sets how many unchanged lines Compare prints around each change, when it renders the differences between
+// long strings or []byte as a unified diff. The default is three lines.
+func DiffContext(lines int) CompareOption {
+	return func(c *comparer) {
+		c.contextLines = lines
+	}
+}
+
+// HighlightCharacters makes Compare highlight the characters that changed inside of each line of a unified diff,
+// instead of the words
+func HighlightCharacters() CompareOption {
+	return func(c *comparer) {
+		c.isHighlightingCharacters = true
+	}
+}
+
+// comparers holds the funcs that have been registered by RegisterComparer, by the type that they compare
+var comparers sync.Map
+
+// RegisterComparer registers a func that decides whether two values of a type are equal, so that Diff and Compare use
+// it instead of comparing values of that type field by field, eg.
+// RegisterComparer(func(a, b Money) bool { return a.Cents == b.Cents }). It panics if fn is any other kind of value.
+func RegisterComparer(fn interface{}) {
+	t, fnValue := comparerFunc(fn)
+	comparers.Store(t, fnValue)
+}
+
+// UseComparer is like RegisterComparer, but fn is only used by the call to Diff or Compare that it is passed into
+func UseComparer(fn interface{}) CompareOption {
+	t, fnValue := comparerFunc(fn)
+	return func(c *comparer) {
+		if c.comparers == nil {
+			c.comparers = make(map[reflect.Type]reflect.Value)
+		}
+		c.comparers[t] = fnValue
+	}
+}
+
+// comparerFunc returns the type that fn compares, and panics if fn isn't a func(a, b T) bool
+func comparerFunc(fn interface{}) (reflect.Type, reflect.Value) {
+	fnValue := reflect.ValueOf(fn)
+	if t := fnValue.Type(); t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != t.In(1) || t.NumOut() != 1 ||
+		t.Out(0).Kind() != reflect.Bool {
+		panic(fmt.Sprintf("sugar: a comparer must be a func(a, b T) bool, not %T", fn))
+	}
+	return fnValue.Type().In(0), fnValue
+}
+
+// Diff performs a deep reflection over two interfaces and returns every difference that it finds between them.
+// Unexported fields are compared too, unless the IgnoreUnexported option is passed in, and fields that are tagged
+// `sugar:"-"` or `sugar:"ignore"` are always skipped. CopyMapped reads its field names from the same tag, and doesn't
+// map the fields that Diff skips. Values of different types, including different types inside of interfaces, are
+// reported as a TypeMismatch. Chans are equal if they are the same chan, and funcs are equal if they are both nil or
+// both run the same code.
+func Diff(a, b interface{}, opts ...CompareOption) []Difference {
+	return diff(a, b, opts).differences
+}
+
+// diff compares a and b and returns the comparer that compared them
+func diff(a, b interface{}, opts []CompareOption) *comparer {
+	c := comparer{timeTolerance: time.Second, contextLines: 3, maxDepth: -1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.compare(reflect.ValueOf(a), reflect.ValueOf(b))
+	return &c
+}
+
+// pair is a pair of pointers, maps or slices that are being compared, identified by what they point to
+type pair struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+// comparer holds the state of a single call to Diff
+type comparer struct {
+	// isStoppingAtFirst stops comparing after the first difference, see StopAtFirstDifference
+	isStoppingAtFirst bool
+
+	// isOmittingJSONIgnored skips fields tagged `json:"-"`, see OmitJSONIgnored
+	isOmittingJSONIgnored bool
+
+	// isIgnoringUnexported skips unexported fields, see IgnoreUnexported
+	isIgnoringUnexported bool
+
+	// ignored holds the paths of the unexported fields that were skipped
+	ignored []string
+
+	// ignoredPaths and ignoredTypes are the values that are skipped, see IgnorePaths and IgnoreTypes
+	ignoredPaths []pattern
+	ignoredTypes map[reflect.Type]bool
+
+	// floatAbs and floatRel are the tolerances for comparing floats, see FloatTolerance
+	floatAbs float64
+	floatRel float64
+
+	// timeTolerance is how far apart two times can be and still be equal, see TimeTolerance
+	timeTolerance time.Duration
+
+	// isIgnoringZeroExpected skips values in a that are zero, see IgnoreZeroExpected
+	isIgnoringZeroExpected bool
+
+	// isDistinguishingNil reports nil slices and maps as different from empty ones, see DistinguishNil
+	isDistinguishingNil bool
+
+	// isIgnoringOrder and unorderedPaths are the slices that are compared as if they were unordered, see IgnoreOrder
+	isIgnoringOrder bool
+	unorderedPaths  []pattern
+
+	// sortKeys are the funcs that slices are sorted by before they are compared, by element type, see SortSlicesBy
+	sortKeys map[reflect.Type]reflect.Value
+
+	// contextLines and isHighlightingCharacters change how differences between texts are rendered, see DiffContext
+	// and HighlightCharacters
+	contextLines             int
+	isHighlightingCharacters bool
+
+	// comparers are the funcs that compare values of a type during this call, see UseComparer
+	comparers map[reflect.Type]reflect.Value
+
+	// isSubset only checks the values that are set in a, and records the paths that it checked, see CompareSubset
+	isSubset bool
+	checked  []string
+
+	// style is the way that Compare renders differences, see UseStyle
+	style Style
+
+	// isUsingPointers prints the paths of differences as JSON pointers, see CompareJSON
+	isUsingPointers bool
+
+	// maxDepth is how deeply nested values are compared, or -1 if there is no limit, see MaxCompareDepth
+	maxDepth int
+
+	// comparing holds the pointers, maps and slices that are being compared further up the path, so that cycles
+	// can be stopped when they come back around
+	comparing map[pair]bool
+
+	// path is the path of the values that are currently being compared
+	path path
+
+	// differences are the differences that have been found so far
+	differences []Difference
+}
+
+// isDone returns true if the comparer shouldn't look for any more differences
+func (c *comparer) isDone() bool {
+	return c.isStoppingAtFirst && len(c.differences) > 0
+}
+
+// at returns the current path, as a JSON pointer if the comparer is using them
+func (c *comparer) at() string {
+	if c.isUsingPointers {
+		return c.path.pointer()
+	}
+	return c.path.String()
+}
+
+// check records that the value at the current path was checked, if the comparer is comparing a subset
+func (c *comparer) check() {
+	if c.isSubset {
+		c.checked = append(c.checked, c.at())
+	}
+}
+
+// add records a difference between a and b at the current path
+func (c *comparer) add(kind ChangeKind, a, b reflect.Value) {
+	c.differences = append(c.differences, Difference{
+		Path:     c.at(),
+		Kind:     kind,
+		Expected: valueOf(a),
+		Found:    valueOf(b),
+	})
+}
+
+// compareAt compares a and b, where a and b are found at step s from the values that are currently being compared
+func (c *comparer) compareAt(s step, a, b reflect.Value) {
+	c.path = append(c.path, s)
+	c.compare(a, b)
+	c.path = c.path[:len(c.path)-1]
+}
+
+// isIgnored returns true if the expected value a at the current path should not be compared
+func (c *comparer) isIgnored(a reflect.Value) bool {
+	if c.isIgnoringZeroExpected && (!a.IsValid() || a.IsZero()) {
+		return true
+	} else if a.IsValid() && c.ignoredTypes[a.Type()] {
+		return true
+	}
+	for _, pt := range c.ignoredPaths {
+		if pt.match(c.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalFunc returns a func that decides whether two values of type t are equal, along with its name. Comparers that
+// were passed into this call come first, then registered comparers and then Equal(T) bool methods.
+func (c *comparer) equalFunc(t reflect.Type) (reflect.Value, string, bool) {
+	if fn, ok := c.comparers[t]; ok {
+		return fn, runtime.FuncForPC(fn.Pointer()).Name(), true
+	} else if fn, ok := comparers.Load(t); ok {
+		return fn.(reflect.Value), runtime.FuncForPC(fn.(reflect.Value).Pointer()).Name(), true
+	}
+
+	// times are compared to within the time tolerance instead of by their Equal method
+	method, ok := t.MethodByName("Equal")
+	if !ok || t == timeType || method.Type.NumIn() != 2 || method.Type.In(1) != t || method.Type.NumOut() != 1 ||
+		method.Type.Out(0).Kind() != reflect.Bool {
+		return reflect.Value{}, "", false
+	}
+	return method.Func, fmt.Sprintf("(%s).Equal", t), true
+}
+
+// isUnordered returns true if the slices at the current path should be compared as if they were unordered
+func (c *comparer) isUnordered() bool {
+	if c.isIgnoringOrder {
+		return true
+	}
+	for _, pt := range c.unorderedPaths {
+		if pt.match(c.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isClose returns true if two floats are within the float tolerance of eachother
+func (c *comparer) isClose(a, b float64) bool {
+	difference := math.Abs(a - b)
+	return a == b || difference <= c.floatAbs || difference <= c.floatRel*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// isEqualNumber returns true if two JSON numbers have exactly the same value, eg. 1.0 and 1, or if they are within the
+// float tolerance
+func (c *comparer) isEqualNumber(a, b json.Number) bool {
+	aRat, aOK := new(big.Rat).SetString(string(a))
+	bRat, bOK := new(big.Rat).SetString(string(b))
+	if !aOK || !bOK {
+		return a == b
+	} else if aRat.Cmp(bRat) == 0 {
+		return true
+	} else if c.floatAbs == 0 && c.floatRel == 0 {
+		return false
+	}
+	aFloat, _ := aRat.Float64()
+	bFloat, _ := bRat.Float64()
+	return c.isClose(aFloat, bFloat)
+}
+
+// compare records the differences between a and b
+func (c *comparer) compare(a, b reflect.Value) {
+
+	// compare the values inside of interfaces
+	if a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+
+	if c.isIgnored(a) || (c.maxDepth >= 0 && len(c.path) > c.maxDepth) {
+		return
+	} else if !a.IsValid() || !b.IsValid() {
+		// a or b is nil
+		if a.IsValid() != b.IsValid() {
+			c.check()
+			c.add(Changed, a, b)
+		}
+		return
+	} else if a.Type() != b.Type() {
+		c.check()
+		c.add(TypeMismatch, a, b)
+		return
+	}
+
+	if equal, name, ok := c.equalFunc(a.Type()); ok && (a.Kind() != reflect.Ptr || (!a.IsNil() && !b.IsNil())) {
+		c.check()
+		if !equal.Call([]reflect.Value{a, b})[0].Bool() {
+			c.add(Changed, a, b)
+			c.differences[len(c.differences)-1].Comparer = name
+		}
+		return
+	}
+
+	// if a and b are already being compared further up the path then they both cycle back to the same place here
+	if k := a.Kind(); (k == reflect.Ptr || k == reflect.Map || k == reflect.Slice) && !a.IsNil() && !b.IsNil() {
+		p := pair{a.Pointer(), b.Pointer(), a.Type()}
+		if c.comparing[p] {
+			return
+		} else if c.comparing == nil {
+			c.comparing = make(map[pair]bool)
+		}
+		c.comparing[p] = true
+		defer delete(c.comparing, p)
+	}
+
+	if a.Kind() == reflect.Ptr {
+		// a and b point to non nil values
+		if !a.IsNil() && !b.IsNil() {
+			c.compare(a.Elem(), b.Elem())
+		} else if a.IsNil() != b.IsNil() {
+			c.check()
+			c.add(Changed, a, b)
+		}
+
+	} else if (a.Kind() == reflect.Slice || a.Kind() == reflect.Map) && c.isDistinguishingNil && a.IsNil() != b.IsNil() {
+		c.check()
+		c.add(Changed, a, b)
+
+	} else if a.Kind() == reflect.Slice {
+		if key, ok := c.sortKeys[a.Type().Elem()]; ok {
+			a, b = sortedSlice(a, key), sortedSlice(b, key)
+		}
+		if c.isUnordered() {
+			c.compareUnordered(a, b)
+			return
+		} else if a.Type().Elem().Kind() == reflect.Uint8 {
+			// bytes are compared all at once, so that they can be rendered as text or as a hexdump
+			c.check()
+			if !bytes.Equal(a.Bytes(), b.Bytes()) {
+				c.add(Changed, a, b)
+			}
+			return
+		}
+
+		// compare the items that are in both slices, then the items that are only in one of them
+		for i, l := 0, a.Len(); i < l && i < b.Len() && !c.isDone(); i++ {
+			c.compareAt(indexStep(i), a.Index(i), b.Index(i))
+		}
+		for i, l := b.Len(), a.Len(); i < l && !c.isDone(); i++ {
+			c.path = append(c.path, indexStep(i))
+			c.check()
+			c.add(Removed, a.Index(i), reflect.Value{})
+			c.path = c.path[:len(c.path)-1]
+		}
+		for i, l := a.Len(), b.Len(); i < l && !c.isDone() && !c.isSubset; i++ {
+			c.path = append(c.path, indexStep(i))
+			c.add(Added, reflect.Value{}, b.Index(i))
+			c.path = c.path[:len(c.path)-1]
+		}
+
+	} else if a.Kind() == reflect.Array {
+		for i, l := 0, a.Len(); i < l && !c.isDone(); i++ {
+			c.compareAt(indexStep(i), a.Index(i), b.Index(i))
+		}
+
+	} else if a.Kind() == reflect.Map {
+		// see if there are any keys that are missing, unexpected or that have different values
+		for _, key := range sortedKeys(a, b) {
+			if c.isDone() {
+				break
+			}
+			aItem, bItem := a.MapIndex(key), b.MapIndex(key)
+			c.path = append(c.path, keyStep(key))
+			if !bItem.IsValid() {
+				c.check()
+				c.add(Removed, aItem, bItem)
+			} else if !aItem.IsValid() {
+				if !c.isSubset {
+					c.add(Added, aItem, bItem)
+				}
+			} else {
+				c.compare(aItem, bItem)
+			}
+			c.path = c.path[:len(c.path)-1]
+		}
+
+	} else if a.Type() == timeType {
+		// compare times to within the time tolerance
+		c.check()
+		aTime, bTime := a.Interface().(time.Time), b.Interface().(time.Time)
+		if time.Duration(math.Abs(float64(aTime.Sub(bTime)))) > c.timeTolerance {
+			c.add(Changed, a, b)
+		}
+
+	} else if a.Type() == jsonNumberType {
+		// compare JSON numbers exactly, unless there is a float tolerance
+		c.check()
+		if !c.isEqualNumber(a.Interface().(json.Number), b.Interface().(json.Number)) {
+			c.add(Changed, a, b)
+		}
+
+	} else if a.Kind() == reflect.Float32 || a.Kind() == reflect.Float64 {
+		c.check()
+		if !c.isClose(a.Float(), b.Float()) {
+			c.add(Changed, a, b)
+		}
+
+	} else if a.Kind() == reflect.Struct {
+		// unexported fields can only be interfaced through a struct that can be addressed
+		a, b = addressable(a), addressable(b)
+
+		// iterate over all of the fields
+		for i, l := 0, a.NumField(); i < l && !c.isDone(); i++ {
+			field := a.Type().Field(i)
+			aField, bField := a.Field(i), b.Field(i)
+
+			// skip fields if they are omited from the json or tagged to be ignored
+			if c.isOmittingJSONIgnored {
+				if jsonTag := field.Tag.Get("json"); len(jsonTag) > 0 && jsonTag[0] == '-' {
+					continue
+				}
+			}
+			if isIgnoredTag(field.Tag) {
+				continue
+			}
+
+			if field.PkgPath != "" {
+				if c.isIgnoringUnexported {
+					c.ignored = append(c.ignored, c.path.String()+"."+field.Name)
+					continue
+				}
+				aField, bField = exported(aField), exported(bField)
+			}
+
+			c.compareAt(fieldStep(field.Name), aField, bField)
+		}
+
+	} else if a.Kind() == reflect.Func {
+		// funcs can't be compared by value, so compare the code that they run
+		c.check()
+		if a.Pointer() != b.Pointer() {
+			c.add(Changed, a, b)
+		}
+
+	} else {
+		// bools, numbers, strings, chans and unsafe pointers are comparable
+		c.check()
+		if a.Interface() != b.Interface() {
+			c.add(Changed, a, b)
+		}
+	}
+}
+
+// compareUnordered records the differences between two slices, matching the elements of a with equal elements of b
+// wherever they are. Elements that are left over are paired up with the element that they have the fewest differences
+// with, and whatever still doesn't have a pair is missing or unexpected.
+func (c *comparer) compareUnordered(a, b reflect.Value) {
+	isMatched := make([]bool, b.Len())
+	var unmatched []int
+	for i := 0; i < a.Len(); i++ {
+		j := 0
+		for ; j < b.Len(); j++ {
+			if !isMatched[j] && len(c.sub(indexStep(i), a.Index(i), b.Index(j), true)) == 0 {
+				break
+			}
+		}
+		if j == b.Len() {
+			unmatched = append(unmatched, i)
+			continue
+		}
+		isMatched[j] = true
+		c.path = append(c.path, indexStep(i))
+		c.check()
+		c.path = c.path[:len(c.path)-1]
+	}
+
+	for _, i := range unmatched {
+		if c.isDone() {
+			return
+		}
+		nearest, nearestDifferences := -1, []Difference(nil)
+		for j := 0; j < b.Len(); j++ {
+			if isMatched[j] {
+				continue
+			}
+			differences := c.sub(indexStep(i), a.Index(i), b.Index(j), false)
+			if nearest < 0 || len(differences) < len(nearestDifferences) {
+				nearest, nearestDifferences = j, differences
+			}
+		}
+		c.path = append(c.path, indexStep(i))
+		c.check()
+		if nearest < 0 {
+			c.add(Removed, a.Index(i), reflect.Value{})
+		} else {
+			isMatched[nearest] = true
+			c.differences = append(c.differences, nearestDifferences...)
+		}
+		c.path = c.path[:len(c.path)-1]
+	}
+
+	for j := 0; j < b.Len() && !c.isDone() && !c.isSubset; j++ {
+		if !isMatched[j] {
+			c.path = append(c.path, indexStep(j))
+			c.add(Added, reflect.Value{}, b.Index(j))
+			c.path = c.path[:len(c.path)-1]
+		}
+	}
+}
+
+// sub compares a and b with the same options as c, without recording anything on c, and returns their differences
+func (c *comparer) sub(s step, a, b reflect.Value, isStoppingAtFirst bool) []Difference {
+	sub := *c
+	sub.isStoppingAtFirst = isStoppingAtFirst || c.isStoppingAtFirst
+	sub.ignored, sub.differences, sub.checked = nil, nil, nil
+	sub.path = append(append(path(nil), c.path...), s)
+	sub.compare(a, b)
+	return sub.differences
+}
+
+// sortedSlice returns a sorted copy of a slice, ordered by the key that key returns for each of its elements
+func sortedSlice(v reflect.Value, key reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return v
+	}
+	indexes := make([]int, v.Len())
+	keys := make([]reflect.Value, v.Len())
+	for i := range indexes {
+		indexes[i], keys[i] = i, key.Call([]reflect.Value{v.Index(i)})[0]
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return isLess(keys[indexes[i]], keys[indexes[j]])
+	})
+	sorted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	for i, index := range indexes {
+		sorted.Index(i).Set(v.Index(index))
+	}
+	return sorted
+}
+
+// isLess returns true if the key a sorts before the key b
+func isLess(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	}
+	return fmt.Sprintf("%+v", a) < fmt.Sprintf("%+v", b)
+}
+
+// valueOf returns the value held by v, or nil if v is not valid
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// sortedKeys returns the keys of both maps, sorted by how they print so that differences are found in a stable order
+func sortedKeys(a, b reflect.Value) []reflect.Value {
+	keys := a.MapKeys()
+	for _, key := range b.MapKeys() {
+		if !a.MapIndex(key).IsValid() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%+v", keys[i]) < fmt.Sprintf("%+v", keys[j])
+	})
+	return keys
+}
@@ -0,0 +1,95 @@
+package sugar
+
+import (
+	"fmt"
+)
+
+// Matcher matches values for Expect. Custom matchers only need to implement this interface to be used with To, ToNot,
+// And, Or and Not.
+type Matcher interface {
+	// Match returns true if actual matches, or an error if actual is a value that can't be matched at all
+	Match(actual interface{}) (bool, error)
+
+	// String describes what the matcher expects, so that it reads like a sentence after "expected <actual> to"
+	String() string
+}
+
+// Expectation is returned by Expect, and checks the value that was passed into Expect against a matcher
+type Expectation interface {
+	// To logs a failure if the value doesn't match, and returns true if it does
+	To(Matcher) bool
+
+	// ToNot logs a failure if the value matches, and returns true if it doesn't
+	ToNot(Matcher) bool
+}
+
+// failure is logged by an expectation that isn't met, so that the test that logged it fails even if it returns true
+type failure string
+
+// Expect starts an expectation about a value that reads like a sentence, eg.
+//
+//	log.Expect(items).To(sugar.HaveLen(3))
+//	log.Expect(err).To(sugar.BeNil())
+//
+// Expectations that aren't met log why they failed and fail the test that they are in, so a test can return true
+// after a series of expectations, or return the result of the last one.
+func (log Log) Expect(actual interface{}) Expectation {
+	return &expectation{log: log, actual: actual}
+}
+
+// expectation is the Expectation that is returned by Expect
+type expectation struct {
+	log    Log
+	actual interface{}
+}
+
+// To logs a failure if the value doesn't match, and returns true if it does
+func (e *expectation) To(m Matcher) bool {
+	return e.match(m, true)
+}
+
+// ToNot logs a failure if the value matches, and returns true if it doesn't
+func (e *expectation) ToNot(m Matcher) bool {
+	return e.match(m, false)
+}
+
+// match matches the value and logs a failure if it isn't what is expected
+func (e *expectation) match(m Matcher, isExpected bool) bool {
+	to := "to"
+	if !isExpected {
+		to = "not to"
+	}
+	isMatched, err := m.Match(e.actual)
+	if err != nil {
+		e.log(failure(fmt.Sprintf("expected %+v %s %s, but %s", e.actual, to, m, err)))
+		return false
+	} else if isMatched == isExpected {
+		return true
+	}
+	e.log(failure(fmt.Sprintf("expected %+v.(%T) %s %s", e.actual, e.actual, to, m)))
+	if m, ok := m.(*explainingMatcher); ok && isExpected {
+		m.explain(e.actual, e.log)
+	}
+	return false
+}
+
+// isFailed returns true if an expectation failed in the logger, or in any of the loggers nested inside of it
+func isFailed(l Logger) bool {
+	nested, ok := l.(*logger)
+	if !ok {
+		return false
+	}
+	nested.mutex.Lock()
+	stack := nested.stack
+	nested.mutex.Unlock()
+	for _, s := range stack {
+		if _, ok := s.(failure); ok {
+			return true
+		} else if e, ok := s.(entry); ok && e.isFailure {
+			return true
+		} else if l, ok := s.(Logger); ok && l != nested && isFailed(l) {
+			return true
+		}
+	}
+	return false
+}
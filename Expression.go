@@ -0,0 +1,317 @@
+package sugar
+
+import (
+	"bytes"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sources caches the source files that have been parsed to find the expressions that tests return
+var sources sync.Map
+
+// source is a parsed source file
+type source struct {
+	fset *token.FileSet
+	file *ast.File
+}
+
+// logReturn logs the return statement that most likely made a test return false, along with the values of the operands
+// of its && and || chains that can be worked out from the source, ie. ones that are made out of literals and constants
+// that are declared in the same file. Go can't read the local variables of a func that has returned, or package-level
+// variables by their name, so operands that use them aren't listed, and nothing is logged if the source of the test
+// can't be found.
+func logReturn(test Test, l Logger) {
+	fn := runtime.FuncForPC(reflect.ValueOf(test).Pointer())
+	if fn == nil {
+		return
+	}
+	filename, line := fn.FileLine(fn.Entry())
+	src := parse(filename)
+	if src == nil {
+		return
+	}
+	body := funcBody(src, fn.Name())
+	if body == nil {
+		body = lineBody(src, line)
+	}
+	if body == nil {
+		return
+	}
+
+	// only returns that could be false are candidates, and the first one after the last line that the test logged
+	// is the most likely one to have failed
+	var candidates []*ast.ReturnStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if len(n.Results) == 1 {
+				if ident, ok := n.Results[0].(*ast.Ident); !ok || ident.Name != "true" {
+					candidates = append(candidates, n)
+				}
+			}
+		}
+		return true
+	})
+	if lastLine := lastLogged(l, filename, src.fset.Position(body.Pos()).Line, src.fset.Position(body.End()).Line); lastLine > 0 {
+		for _, candidate := range candidates {
+			if src.fset.Position(candidate.Pos()).Line >= lastLine {
+				candidates = []*ast.ReturnStmt{candidate}
+				break
+			}
+		}
+	}
+
+	// tag each line with the location of the code that it describes, since it is logged from sugar's own goroutine
+	at := func(n ast.Node) caller {
+		return caller{file: filename, line: src.fset.Position(n.Pos()).Line}
+	}
+	nestedLogger := NewLogger()
+	switch len(candidates) {
+	case 0:
+		return
+	case 1:
+		logAt(l, at(candidates[0]), "returned false from %s", expression(src.fset, candidates[0].Results[0]))
+		if operands := chain(candidates[0].Results[0]); len(operands) > 1 {
+			var isEvaluated bool
+			for _, operand := range operands {
+				// literals like `true` are their own value, so they aren't worth listing
+				if value, ok := evaluate(operand, nil); ok && value.String() != expression(src.fset, unparen(operand)) {
+					logAt(nestedLogger, at(operand), "%s = %s", expression(src.fset, operand), value)
+					isEvaluated = true
+				}
+			}
+			if isEvaluated {
+				logAt(l, at(candidates[0]), nestedLogger)
+			}
+		}
+	default:
+		logAt(l, at(body), "returned false from one of")
+		for _, candidate := range candidates {
+			logAt(nestedLogger, at(candidate), "line %d: %s", src.fset.Position(candidate.Pos()).Line, expression(src.fset, candidate.Results[0]))
+		}
+		logAt(l, at(body), nestedLogger)
+	}
+}
+
+// parse returns a parsed source file, or nil if it can't be parsed
+func parse(filename string) *source {
+	if src, ok := sources.Load(filename); ok {
+		return src.(*source)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		sources.Store(filename, (*source)(nil))
+		return nil
+	}
+	src := &source{fset: fset, file: file}
+	sources.Store(filename, src)
+	return src
+}
+
+// funcBody returns the body of the func with a name like `github.com/user/repo.TestName.func3.2`, which is the second
+// func literal inside of the third func literal inside of TestName, or nil if it can't be found
+func funcBody(src *source, name string) *ast.BlockStmt {
+	name = name[strings.LastIndex(name, "/")+1:]
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 {
+		return nil
+	}
+	parts = parts[1:]
+
+	// find the declaration, which can be a method like `(*Type).Name`
+	var body *ast.BlockStmt
+	for _, decl := range src.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		} else if fn.Recv == nil && fn.Name.Name == parts[0] {
+			body, parts = fn.Body, parts[1:]
+			break
+		} else if fn.Recv != nil && len(parts) > 1 && fn.Name.Name == parts[1] &&
+			strings.Trim(parts[0], "(*)") == receiverName(fn.Recv.List[0].Type) {
+			body, parts = fn.Body, parts[2:]
+			break
+		}
+	}
+
+	// then find each func literal by the order that it appears in the func that it is in
+	for _, part := range parts {
+		n, err := strconv.Atoi(strings.TrimPrefix(part, "func"))
+		if body == nil || err != nil {
+			return nil
+		}
+		var next *ast.BlockStmt
+		ast.Inspect(body, func(node ast.Node) bool {
+			if lit, ok := node.(*ast.FuncLit); ok && next == nil {
+				if n--; n == 0 {
+					next = lit.Body
+				}
+				return false
+			}
+			return next == nil
+		})
+		body = next
+	}
+	return body
+}
+
+// receiverName returns the name of the type of a method's receiver
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// lineBody returns the body of the innermost func that contains line
+func lineBody(src *source, line int) *ast.BlockStmt {
+	var body *ast.BlockStmt
+	ast.Inspect(src.file, func(n ast.Node) bool {
+		var b *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			b = n.Body
+		case *ast.FuncDecl:
+			b = n.Body
+		}
+		if b == nil {
+			return true
+		}
+		start, end := src.fset.Position(b.Lbrace).Line, src.fset.Position(b.Rbrace).Line
+		if start <= line && line <= end {
+			body = b
+		}
+		return true
+	})
+	return body
+}
+
+// lastLogged returns the last line of a file between start and end that was logged, or 0 if nothing was
+func lastLogged(l Logger, filename string, start, end int) int {
+	var lastLine int
+	if l, ok := l.(*logger); ok {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		for _, at := range l.callers {
+			if at.file == filename && start <= at.line && at.line <= end && at.line > lastLine {
+				lastLine = at.line
+			}
+		}
+	}
+	return lastLine
+}
+
+// chain splits a chain of && or || expressions into its operands
+func chain(expr ast.Expr) []ast.Expr {
+	binary, ok := unparen(expr).(*ast.BinaryExpr)
+	if !ok || (binary.Op != token.LAND && binary.Op != token.LOR) {
+		return []ast.Expr{expr}
+	}
+	var operands []ast.Expr
+	for _, operand := range []ast.Expr{binary.X, binary.Y} {
+		if b, ok := unparen(operand).(*ast.BinaryExpr); ok && b.Op == binary.Op {
+			operands = append(operands, chain(b)...)
+		} else {
+			operands = append(operands, operand)
+		}
+	}
+	return operands
+}
+
+// evaluate works out the value of an expression that is made out of literals and the constants that are declared in
+// the same file, and returns false for anything else. seen holds the constants that are being evaluated, so that
+// constants that refer to eachother don't loop forever.
+func evaluate(expr ast.Expr, seen map[*ast.Object]bool) (value constant.Value, ok bool) {
+	// constant.BinaryOp and friends panic on operands that don't go together, like a string and an int
+	defer func() {
+		if recover() != nil {
+			value, ok = nil, false
+		}
+	}()
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return evaluate(e.X, seen)
+	case *ast.BasicLit:
+		value = constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		return value, value.Kind() != constant.Unknown
+	case *ast.Ident:
+		if e.Obj == nil && (e.Name == "true" || e.Name == "false") {
+			return constant.MakeBool(e.Name == "true"), true
+		} else if e.Obj == nil || e.Obj.Kind != ast.Con || seen[e.Obj] {
+			return nil, false
+		}
+		spec, isSpec := e.Obj.Decl.(*ast.ValueSpec)
+		if !isSpec {
+			return nil, false
+		}
+		for i, name := range spec.Names {
+			if name.Name == e.Name && i < len(spec.Values) {
+				if seen == nil {
+					seen = map[*ast.Object]bool{}
+				}
+				seen[e.Obj] = true
+				defer delete(seen, e.Obj)
+				return evaluate(spec.Values[i], seen)
+			}
+		}
+	case *ast.UnaryExpr:
+		if x, ok := evaluate(e.X, seen); ok {
+			return constant.UnaryOp(e.Op, x, 0), true
+		}
+	case *ast.BinaryExpr:
+		x, xOK := evaluate(e.X, seen)
+		y, yOK := evaluate(e.Y, seen)
+		if !xOK || !yOK {
+			return nil, false
+		}
+		switch e.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return constant.MakeBool(constant.Compare(x, e.Op, y)), true
+		case token.SHL, token.SHR:
+			shift, isExact := constant.Uint64Val(y)
+			return constant.Shift(x, e.Op, uint(shift)), isExact
+		case token.QUO, token.REM:
+			if constant.Sign(y) == 0 {
+				return nil, false
+			} else if e.Op == token.QUO && x.Kind() == constant.Int && y.Kind() == constant.Int {
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y), true
+			}
+		}
+		return constant.BinaryOp(x, e.Op, y), true
+	}
+	return nil, false
+}
+
+// unparen removes the parentheses around an expression
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+// expression prints the source of an expression
+func expression(fset *token.FileSet, expr ast.Expr) string {
+	var buffer bytes.Buffer
+	printer.Fprint(&buffer, fset, expr)
+	return buffer.String()
+}
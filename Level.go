@@ -0,0 +1,195 @@
+package sugar
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Level is the level of a line that is logged with Debug, Info, Warn or Error
+type Level int
+
+const (
+	// DebugLevel is for diagnostic lines that are only worth reading when something fails
+	DebugLevel Level = iota
+
+	// InfoLevel is for lines that describe what a test is doing
+	InfoLevel
+
+	// WarnLevel is for lines about things that look wrong, but that don't fail a test on their own
+	WarnLevel
+
+	// ErrorLevel is for lines about things that are wrong
+	ErrorLevel
+)
+
+// levelNames are the names of the levels, as they are passed into the `-sugar.loglevel` flag
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+// levelColors are the colors that the lines of each level are printed in
+var levelColors = map[Level]func(interface{}) string{
+	DebugLevel: grayColor,
+	InfoLevel:  cyanColor,
+	WarnLevel:  yellowColor,
+	ErrorLevel: redColor,
+}
+
+// passingLevel is the lowest level that is printed for assertions that pass, set by the `-sugar.loglevel` flag.
+// Every line is printed for assertions that fail.
+var passingLevel = DebugLevel
+
+func init() {
+	flag.Var(&passingLevel, "sugar.loglevel", "the lowest level that is logged for passing tests: debug, info, warn or error")
+}
+
+// String returns the name of the level
+func (level Level) String() string {
+	return levelNames[level]
+}
+
+// Set sets the level by its name, so that a Level can be used as a flag
+func (level *Level) Set(name string) error {
+	for l, levelName := range levelNames {
+		if levelName == name {
+			*level = l
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a level, use debug, info, warn or error", name)
+}
+
+// messageWidth is how wide messages are padded to, so that the fields after them line up
+const messageWidth = 32
+
+// entry is a line that was logged with a level or with fields
+type entry struct {
+	level   Level
+	message string
+	fields  []field
+	// isLeveled is false for lines that were logged with fields but without a level
+	isLeveled bool
+	// isFailure is true for failures that were logged with fields, so that they still fail the test, see isFailed
+	isFailure bool
+}
+
+// field is a key and a value that were added to a line with With
+type field struct {
+	key   string
+	value interface{}
+}
+
+// String prints the level and the message in the level's color, followed by each field as a key=value pair that lines
+// up with the fields of the lines around it
+func (e entry) String() string {
+	message := e.message
+	if len(e.fields) > 0 {
+		if width := utf8.RuneCountInString(message); width < messageWidth {
+			message += strings.Repeat(" ", messageWidth-width)
+		}
+	}
+	if e.isLeveled {
+		message = levelColors[e.level](fmt.Sprintf("%-5s %s", strings.ToUpper(e.level.String()), message))
+	}
+	for _, f := range e.fields {
+		message += fmt.Sprintf(" %s=%+v", grayColor(f.key), f.value)
+	}
+	return message
+}
+
+// Debug logs a line at the debug level, which can be hidden for passing tests with the `-sugar.loglevel` flag
+func (log Log) Debug(format string, args ...interface{}) {
+	log(newEntry(DebugLevel, format, args))
+}
+
+// Info logs a line at the info level
+func (log Log) Info(format string, args ...interface{}) {
+	log(newEntry(InfoLevel, format, args))
+}
+
+// Warn logs a line at the warn level
+func (log Log) Warn(format string, args ...interface{}) {
+	log(newEntry(WarnLevel, format, args))
+}
+
+// Error logs a line at the error level
+func (log Log) Error(format string, args ...interface{}) {
+	log(newEntry(ErrorLevel, format, args))
+}
+
+// With returns a Log that adds a key and a value to every line that it logs, eg.
+//
+//	log.With("user", id).With("attempt", 2).Info("created")
+//
+// Nested loggers are logged without the fields, since they don't have a line of their own to put them on.
+func (log Log) With(key string, value interface{}) Log {
+	return func(s interface{}, args ...interface{}) {
+		var e entry
+		switch v := s.(type) {
+		case Logger:
+			log(s, args...)
+			return
+		case entry:
+			// entries can be followed by the loggers that are nested underneath them, eg. the groups of a slog record
+			v.fields = append([]field{{key, value}}, v.fields...)
+			log(v, args...)
+			return
+		case failure:
+			e = entry{message: string(v), isFailure: true}
+		case string:
+			e = entry{message: v}
+			if args != nil {
+				e.message = fmt.Sprintf(v, args...)
+				args = nil
+			}
+		default:
+			e = entry{message: fmt.Sprintf("%+v", s)}
+		}
+		for _, arg := range args {
+			e.message += fmt.Sprintf(" %+v", arg)
+		}
+		e.fields = append([]field{{key, value}}, e.fields...)
+		log(e)
+	}
+}
+
+// newEntry returns a line at a level, formatting the message like fmt.Sprintf if there are any args
+func newEntry(level Level, format string, args []interface{}) entry {
+	if len(args) > 0 {
+		format = fmt.Sprintf(format, args...)
+	}
+	return entry{level: level, message: format, isLeveled: true}
+}
+
+// visible returns the lines of a logger that should be printed for a test that passed, leaving out the ones that are
+// below the `-sugar.loglevel` flag along with the loggers that are nested right after them
+func visible(l Logger) Logger {
+	original, ok := l.(*logger)
+	if !ok || passingLevel == DebugLevel {
+		return l
+	}
+	original.mutex.Lock()
+	defer original.mutex.Unlock()
+	filtered := &logger{out: original.out, style: original.style}
+	var isHidden bool
+	for i, s := range original.stack {
+		nested, isNested := s.(Logger)
+		if e, ok := s.(entry); ok && e.isLeveled && e.level < passingLevel {
+			isHidden = true
+			continue
+		} else if isNested && isHidden {
+			continue
+		} else if isNested && nested != Logger(original) {
+			s = visible(nested)
+		}
+		isHidden = false
+		filtered.stack = append(filtered.stack, s)
+		filtered.callers = append(filtered.callers, original.callers[i])
+	}
+	return filtered
+}
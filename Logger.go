@@ -3,18 +3,17 @@ package sugar
 import (
 	"fmt"
 	"io"
-	"math"
 	"os"
-	"reflect"
-	"runtime"
 	"strings"
-	"time"
+	"sync"
 )
 
 type logger struct {
-	stack []interface{}
-	lines []int
-	out   io.Writer
+	mutex   sync.Mutex
+	stack   []interface{}
+	callers []caller
+	out     io.Writer
+	style   Style
 }
 
 // Log lines in yellow in the following format:
@@ -25,7 +24,7 @@ type Log func(s interface{}, args ...interface{})
 
 // Logger will print nested logs. So, if you are logging recursively, create new Loggers and then log them after the function
 // returns. You can optionally pass in different writers to be used to write the output of the writer the default output is
-// io.Stdout
+// io.Stdout. Loggers are safe to use from more than one goroutine, and print lines in the order that they were logged.
 type Logger interface {
 	// Logs lines and other loggers nested underneath a tests. Here is an example of the output:
 	//  ┠ this is what a log looks line
@@ -33,8 +32,38 @@ type Logger interface {
 	//  ┖  ┖ finally, its possible to nest logs by createing a new logger
 	Log(s interface{}, args ...interface{})
 
-	// // Compare compares interface `b` against interface `a` and logs all of the differences
-	Compare(a, b interface{}, omitEmpty ...bool) bool
+	// Compare compares interface `b` against interface `a` and logs all of the differences
+	Compare(a, b interface{}, opts ...CompareOption) bool
+
+	// CompareJSON compares two JSON documents and logs all of the differences
+	CompareJSON(expected, actual interface{}, opts ...CompareOption) bool
+
+	// CompareSubset compares the values that are set in `expected` against `actual` and logs all of the differences
+	CompareSubset(expected, actual interface{}, opts ...CompareOption) bool
+
+	// Debug logs a line at the debug level, which is hidden for passing tests by `-sugar.loglevel=info` or higher
+	Debug(format string, args ...interface{})
+
+	// Info logs a line at the info level
+	Info(format string, args ...interface{})
+
+	// Warn logs a line at the warn level
+	Warn(format string, args ...interface{})
+
+	// Error logs a line at the error level
+	Error(format string, args ...interface{})
+
+	// With returns a Log that adds a key=value field to every line that it logs, see Log.With
+	With(key string, value interface{}) Log
+
+	// Expect starts an expectation about a value, see Log.Expect
+	Expect(actual interface{}) Expectation
+
+	// Helper marks the func that calls it as a helper, see Log.Helper
+	Helper()
+
+	// SetStyle sets the style that Compare renders differences in for this logger
+	SetStyle(style Style)
 
 	// Prints the log
 	String() string
@@ -53,121 +82,103 @@ func NewLogger(outs ...io.Writer) Logger {
 //  ┠ &{Field:1}
 //  ┖  ┖ finally, its possible to nest logs by createing a new logger
 func (l *logger) Log(s interface{}, args ...interface{}) {
-	// TODO: determine the part of the call stack that actually called this function, see #6
-	_, _, line, _ := runtime.Caller(2)
+	l.logAt(callerOf(), s, args...)
+}
+
+// logAt logs lines the same way that Log does, but tags them with a location instead of with the location that called it
+func (l *logger) logAt(at caller, s interface{}, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	if args != nil {
 		if str, ok := s.(string); ok {
 			l.stack = append(l.stack, fmt.Sprintf(str, args...))
-			l.lines = append(l.lines, line)
+			l.callers = append(l.callers, at)
 		} else {
 			l.stack = append(l.stack, s)
-			l.lines = append(l.lines, line)
+			l.callers = append(l.callers, at)
 			for _, arg := range args {
 				l.stack = append(l.stack, arg)
-				l.lines = append(l.lines, line)
+				l.callers = append(l.callers, at)
 			}
 		}
 	} else if s != nil {
 		l.stack = append(l.stack, s)
-		l.lines = append(l.lines, line)
+		l.callers = append(l.callers, at)
 	}
 }
 
 // Compare performs a deep reflection over two interfaces and logs any differences that it finds. It returns true if the two
 // interfaces match eachother.
-func (l *logger) Compare(a, b interface{}, omitEmpty ...bool) bool {
-	return Log(l.Log).Compare(a, b, omitEmpty...)
+func (l *logger) Compare(a, b interface{}, opts ...CompareOption) bool {
+	return Log(l.Log).Compare(a, b, l.options(opts)...)
 }
 
-// Compare performs a deep reflection over two interfaces and logs any differences that it finds. It returns true if the two
-// interfaces match eachother.
-func (log Log) Compare(a, b interface{}, omitEmpty ...bool) bool {
+// CompareJSON decodes two JSON documents and logs any differences between them by their JSON pointer. It returns true
+// if the two documents match eachother.
+func (l *logger) CompareJSON(expected, actual interface{}, opts ...CompareOption) bool {
+	return Log(l.Log).CompareJSON(expected, actual, l.options(opts)...)
+}
 
-	aValue := reflect.ValueOf(a)
-	bValue := reflect.ValueOf(b)
+// CompareSubset compares the values that are set in expected against actual and logs which values it checked and any
+// differences that it found. It returns true if actual matches expected.
+func (l *logger) CompareSubset(expected, actual interface{}, opts ...CompareOption) bool {
+	return Log(l.Log).CompareSubset(expected, actual, l.options(opts)...)
+}
 
-	var isOmit bool
-	if len(omitEmpty) > 0 {
-		isOmit = omitEmpty[0]
-	}
+// options puts the logger's style in front of the options passed into one of its compare methods
+func (l *logger) options(opts []CompareOption) []CompareOption {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]CompareOption{UseStyle(l.style)}, opts...)
+}
 
-	if aValue.Kind() == reflect.Ptr {
-		// a and b are not both pointers
-		if bValue.Kind() != reflect.Ptr {
-			log("expected: %+v", aValue.Interface())
-			log("found   : %+v", bValue.Interface())
-			return false
-		}
+// Helper marks the func that calls it as a helper, so that lines that are logged from inside of it are tagged with
+// the location that it was called from instead
+func (l *logger) Helper() {
+	markHelper()
+}
 
-		// a and b point to non nil values
-		if aValue.Elem().IsValid() && bValue.Elem().IsValid() {
-			return log.Compare(aValue.Elem().Interface(), bValue.Elem().Interface(), omitEmpty...)
-		}
+// Debug logs a line at the debug level
+func (l *logger) Debug(format string, args ...interface{}) {
+	Log(l.Log).Debug(format, args...)
+}
 
-		// a and b are either both nil and they are the same pointer type
-		return aValue.Elem().IsValid() == bValue.Elem().IsValid() && a == b
-
-	} else if aValue.Kind() == reflect.Slice {
-		// fail if there are different amounts of items in the slices
-		if aValue.Len() != bValue.Len() {
-			log("%s", aValue.Type())
-			nestedLogger := NewLogger()
-			nestedLogger.Log("expected: %d", aValue.Len())
-			nestedLogger.Log("found   : %d", bValue.Len())
-			log(nestedLogger)
-			return false
-		}
-		// see if there is anything we expect that isn't in the structValues
-		for i, l := 0, aValue.Len(); i < l; i++ {
-			nestedLogger := NewLogger()
-			if !Log(nestedLogger.Log).Compare(aValue.Index(i).Interface(), bValue.Index(i).Interface(), omitEmpty...) {
-				log("%s failed at index %d", aValue.Type(), i)
-				log(nestedLogger)
-				return false
-			}
-		}
-		return true
-	} else if aTime, ok := aValue.Interface().(time.Time); ok {
-		bTime, _ := bValue.Interface().(time.Time)
-		// compare times to the nearest second
-		if time.Duration(math.Abs(float64(aTime.Sub(bTime)))) > time.Second {
-			log("expected: %v.(%s)", aTime, aValue.Type())
-			log("found   : %v.(%s)", bTime, bValue.Type())
-			return false
-		}
-		return true
-	} else if aValue.Kind() == reflect.Struct {
-		// iterate over all of the field
-		for i, l := 0, aValue.NumField(); i < l; i++ {
-			aField := aValue.Type().Field(i)
-			aFieldValue := aValue.FieldByName(aField.Name)
-			bField := bValue.Type().Field(i)
-			bFieldValue := bValue.FieldByName(bField.Name)
-
-			// skip fields if they are omited from the json
-			if isOmit {
-				if jsonTag := aField.Tag.Get("json"); len(jsonTag) > 0 && jsonTag[0] == '-' {
-					continue
-				}
-			}
+// Info logs a line at the info level
+func (l *logger) Info(format string, args ...interface{}) {
+	Log(l.Log).Info(format, args...)
+}
 
-			nestedLogger := NewLogger()
-			if !Log(nestedLogger.Log).Compare(aFieldValue.Interface(), bFieldValue.Interface()) {
-				log("%s.%s", aValue.Type(), aField.Name)
-				log(nestedLogger)
-				return false
-			}
-		}
-		return true
-	} else if a != b {
-		log("expected: %v.(%s)", a, aValue.Type())
-		log("found   : %v.(%s)", b, bValue.Type())
-		return false
-	}
-	return true
+// Warn logs a line at the warn level
+func (l *logger) Warn(format string, args ...interface{}) {
+	Log(l.Log).Warn(format, args...)
+}
+
+// Error logs a line at the error level
+func (l *logger) Error(format string, args ...interface{}) {
+	Log(l.Log).Error(format, args...)
+}
+
+// With returns a Log that adds a key=value field to every line that it logs into this logger
+func (l *logger) With(key string, value interface{}) Log {
+	return Log(l.Log).With(key, value)
+}
+
+// Expect starts an expectation about a value, which logs a failure into this logger if it isn't met
+func (l *logger) Expect(actual interface{}) Expectation {
+	return Log(l.Log).Expect(actual)
+}
+
+// SetStyle sets the style that Compare renders differences in for this logger, instead of the style that is set by the
+// `-sugar.diff` flag
+func (l *logger) SetStyle(style Style) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.style = style
 }
 
 func (l *logger) String() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	var result string
 	for i, s := 0, len(l.stack); i < s; i++ {
 		isLastLog := i == s-1
@@ -193,11 +204,16 @@ func (l *logger) String() string {
 				logLine = strings.TrimSpace(logLine)
 				isFirstLogLine := j == 0
 				if isFirstLogLine {
-					// print the first line of a multiline log with its `tag` in front and its `[line:#]` at the end
-					result += fmt.Sprintf(" %s %s %s \n",
+					// print the first line of a multiline log with its `tag` in front and its `[file.go:#]` at the end,
+					// unless it was logged from somewhere that doesn't have a location
+					var location string
+					if l.callers[i].file != "" {
+						location = grayColor(fmt.Sprintf("[%s]", l.callers[i])) + " "
+					}
+					result += fmt.Sprintf(" %s %s %s\n",
 						yellowColor(tag),
 						yellowColor(logLine),
-						grayColor(fmt.Sprintf("[line:%d]", l.lines[i])),
+						location,
 					)
 				} else if isLastLog {
 					// add an indent to every log line after the first line
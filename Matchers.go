@@ -0,0 +1,276 @@
+package sugar
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// matcher is a Matcher made out of a description and a func
+type matcher struct {
+	description string
+	match       func(actual interface{}) (bool, error)
+}
+
+// Match returns true if actual matches
+func (m *matcher) Match(actual interface{}) (bool, error) {
+	return m.match(actual)
+}
+
+// String describes what the matcher expects
+func (m *matcher) String() string {
+	return m.description
+}
+
+// explainingMatcher is a Matcher that logs why a value didn't match it, after the failure has been logged
+type explainingMatcher struct {
+	Matcher
+	explain func(actual interface{}, log Log)
+}
+
+// Equal matches values that are deeply equal to expected, the same way that Compare compares them. When a struct,
+// slice, array, map or pointer doesn't match, its differences are logged the way that Compare logs them.
+func Equal(expected interface{}, opts ...CompareOption) Matcher {
+	return &explainingMatcher{
+		&matcher{fmt.Sprintf("equal %+v", expected), func(actual interface{}) (bool, error) {
+			return len(Diff(expected, actual, opts...)) == 0, nil
+		}},
+		func(actual interface{}, log Log) {
+			switch reflect.ValueOf(expected).Kind() {
+			case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+				log.Compare(expected, actual, opts...)
+			}
+		},
+	}
+}
+
+// HaveLen matches arrays, chans, maps, slices and strings that have a length of n
+func HaveLen(n int) Matcher {
+	return &matcher{fmt.Sprintf("have a length of %d", n), func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		switch v.Kind() {
+		case reflect.Array, reflect.Chan, reflect.Map, reflect.Slice, reflect.String:
+			return v.Len() == n, nil
+		}
+		return false, fmt.Errorf("a %T doesn't have a length", actual)
+	}}
+}
+
+// ContainElement matches arrays, slices and maps that have an element that equals element, or that matches element if
+// it is a Matcher
+func ContainElement(element interface{}) Matcher {
+	return &matcher{describe(element, "contain the element", "contain an element that can"), func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		var elements []reflect.Value
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				elements = append(elements, v.Index(i))
+			}
+		case reflect.Map:
+			for iter := v.MapRange(); iter.Next(); {
+				elements = append(elements, iter.Value())
+			}
+		default:
+			return false, fmt.Errorf("a %T doesn't have elements", actual)
+		}
+		return containsMatch(element, elements)
+	}}
+}
+
+// HaveKey matches maps that have a key that equals key, or that matches key if it is a Matcher
+func HaveKey(key interface{}) Matcher {
+	return &matcher{describe(key, "have the key", "have a key that can"), func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		if v.Kind() != reflect.Map {
+			return false, fmt.Errorf("a %T doesn't have keys", actual)
+		}
+		return containsMatch(key, v.MapKeys())
+	}}
+}
+
+// MatchRegexp matches strings and []byte that match a regular expression
+func MatchRegexp(expression string) Matcher {
+	return &matcher{fmt.Sprintf("match the regexp %q", expression), func(actual interface{}) (bool, error) {
+		r, err := regexp.Compile(expression)
+		if err != nil {
+			return false, err
+		}
+		switch s := actual.(type) {
+		case string:
+			return r.MatchString(s), nil
+		case []byte:
+			return r.Match(s), nil
+		}
+		return false, fmt.Errorf("a %T isn't a string", actual)
+	}}
+}
+
+// BeNil matches nil, and chans, funcs, interfaces, maps, pointers and slices that are nil
+func BeNil() Matcher {
+	return &matcher{"be nil", func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		switch v.Kind() {
+		case reflect.Invalid:
+			return true, nil
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			return v.IsNil(), nil
+		}
+		return false, nil
+	}}
+}
+
+// BeZero matches nil and the zero value of any type
+func BeZero() Matcher {
+	return &matcher{"be zero", func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		return !v.IsValid() || v.IsZero(), nil
+	}}
+}
+
+// BeTrue matches true
+func BeTrue() Matcher {
+	return &matcher{"be true", func(actual interface{}) (bool, error) {
+		isTrue, ok := actual.(bool)
+		if !ok {
+			return false, fmt.Errorf("a %T isn't a bool", actual)
+		}
+		return isTrue, nil
+	}}
+}
+
+// BeNumerically matches numbers that compare to value with one of the operators ==, !=, <, <=, > or >=, eg.
+// BeNumerically(">", 3). Numbers of any kind can be compared with eachother, and they are compared by their exact
+// values, so large int64 and uint64 ids aren't rounded the way they would be as float64s.
+func BeNumerically(operator string, value interface{}) Matcher {
+	return &matcher{fmt.Sprintf("be %s %+v", operator, value), func(actual interface{}) (bool, error) {
+		a, err := number(actual)
+		if err != nil {
+			return false, err
+		}
+		b, err := number(value)
+		if err != nil {
+			return false, err
+		}
+		switch comparison := a.Cmp(b); operator {
+		case "==":
+			return comparison == 0, nil
+		case "!=":
+			return comparison != 0, nil
+		case "<":
+			return comparison < 0, nil
+		case "<=":
+			return comparison <= 0, nil
+		case ">":
+			return comparison > 0, nil
+		case ">=":
+			return comparison >= 0, nil
+		}
+		return false, fmt.Errorf("%q isn't an operator", operator)
+	}}
+}
+
+// MatchError matches errors that are, or that wrap, the error target. target can also be a string that the error's
+// message has to equal, or a Matcher that the error's message has to match.
+func MatchError(target interface{}) Matcher {
+	return &matcher{describe(target, "match the error", "have an error message that can"), func(actual interface{}) (bool, error) {
+		err, ok := actual.(error)
+		if !ok || err == nil {
+			return false, fmt.Errorf("a %T isn't an error", actual)
+		}
+		switch t := target.(type) {
+		case error:
+			return errors.Is(err, t), nil
+		case string:
+			return err.Error() == t, nil
+		case Matcher:
+			return t.Match(err.Error())
+		}
+		return false, fmt.Errorf("a %T can't be matched against an error", target)
+	}}
+}
+
+// And matches values that match every one of the matchers
+func And(matchers ...Matcher) Matcher {
+	return &matcher{join(matchers, " and "), func(actual interface{}) (bool, error) {
+		for _, m := range matchers {
+			if isMatched, err := m.Match(actual); err != nil || !isMatched {
+				return false, err
+			}
+		}
+		return true, nil
+	}}
+}
+
+// Or matches values that match any one of the matchers
+func Or(matchers ...Matcher) Matcher {
+	return &matcher{join(matchers, " or "), func(actual interface{}) (bool, error) {
+		for _, m := range matchers {
+			if isMatched, err := m.Match(actual); err != nil {
+				return false, err
+			} else if isMatched {
+				return true, nil
+			}
+		}
+		return false, nil
+	}}
+}
+
+// Not matches values that don't match the matcher
+func Not(m Matcher) Matcher {
+	return &matcher{"not " + m.String(), func(actual interface{}) (bool, error) {
+		isMatched, err := m.Match(actual)
+		return !isMatched && err == nil, err
+	}}
+}
+
+// describe describes an expected value with a prefix, or describes an expected Matcher with another prefix
+func describe(expected interface{}, valuePrefix, matcherPrefix string) string {
+	if m, ok := expected.(Matcher); ok {
+		return matcherPrefix + " " + m.String()
+	}
+	return fmt.Sprintf("%s %+v", valuePrefix, expected)
+}
+
+// join describes a list of matchers
+func join(matchers []Matcher, separator string) string {
+	descriptions := make([]string, len(matchers))
+	for i, m := range matchers {
+		descriptions[i] = m.String()
+	}
+	return strings.Join(descriptions, separator)
+}
+
+// containsMatch returns true if one of the values equals expected, or matches expected if it is a Matcher
+func containsMatch(expected interface{}, values []reflect.Value) (bool, error) {
+	for _, v := range values {
+		if m, ok := expected.(Matcher); ok {
+			if isMatched, err := m.Match(valueOf(v)); err == nil && isMatched {
+				return true, nil
+			}
+		} else if len(Diff(expected, valueOf(v), StopAtFirstDifference())) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// number converts any kind of number to its exact value
+func number(n interface{}) (*big.Rat, error) {
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Rat).SetUint64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if r := new(big.Rat).SetFloat64(v.Float()); r != nil {
+			return r, nil
+		}
+		return nil, fmt.Errorf("%v can't be compared", n)
+	}
+	return nil, fmt.Errorf("a %T isn't a number", n)
+}
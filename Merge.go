@@ -0,0 +1,123 @@
+package sugar
+
+import (
+	"reflect"
+)
+
+// Merge copies the fields in src that are not zero onto dst, leaving the rest of dst as it is. Pointers to values
+// that are not structs are copied whenever they are not nil, so a pointer to a zero value can be used to explicitly
+// set a field. Slices and maps in src replace the ones in dst, unless the AppendSlices or MergeMaps options are passed
+// in. Merge accepts all of the other options that Copy does, and returns the paths of every value in dst that it
+// changed.
+//
+// Example
+//
+//	fixture := Model{ID: 1, Name: "name", Tags: []string{"a"}}
+//	changed, err := sugar.Merge(&fixture, Model{Name: "other", Tags: []string{"b"}}, sugar.AppendSlices())
+//	// fixture == Model{ID: 1, Name: "other", Tags: []string{"a", "b"}}
+//	// changed == []string{"Model.Name", "Model.Tags"}
+func Merge(dst, src interface{}, opts ...CopyOption) ([]string, error) {
+	srcValue := reflect.ValueOf(src)
+	dstValue := reflect.ValueOf(dst)
+	c := newCopier(srcValue, opts)
+	if !dstValue.IsValid() || dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
+		return nil, c.errorf("tried to merge into %v, which is not a pointer", dstValue)
+	} else if srcValue.IsValid() && srcValue.Kind() != reflect.Ptr {
+		dstValue = dstValue.Elem()
+	}
+	err := c.merge(srcValue, dstValue)
+	return c.changed, err
+}
+
+// AppendSlices makes Merge append the slices in src onto the slices in dst instead of replacing them
+func AppendSlices() CopyOption {
+	return func(c *copier) {
+		c.isAppendingSlices = true
+	}
+}
+
+// MergeMaps makes Merge copy the keys of the maps in src into the maps in dst instead of replacing them
+func MergeMaps() CopyOption {
+	return func(c *copier) {
+		c.isMergingMaps = true
+	}
+}
+
+// merge copies a onto b if a is not zero, and records the paths in b that it changes
+func (c *copier) merge(a, b reflect.Value) error {
+	if !a.IsValid() || a.IsZero() {
+		return nil
+	} else if a.Type() != b.Type() {
+		return c.errorf("tried to merge incompatible types %s and %s", a.Type(), b.Type())
+	}
+
+	switch {
+	case a.Kind() == reflect.Ptr && a.Elem().Kind() == reflect.Struct && !c.plan(a.Elem().Type()).isOpaque:
+		// merge into the struct that b points to, making sure that we stop if we've been here before
+		key := c.key(a, b)
+		if _, ok := c.visited[key]; ok {
+			return nil
+		}
+		c.visited[key] = b
+		if b.IsNil() {
+			b.Set(reflect.New(b.Type().Elem()))
+		}
+		return c.merge(a.Elem(), b.Elem())
+	case a.Kind() == reflect.Struct && !c.plan(a.Type()).isOpaque:
+		if c.isCopyingUnexported {
+			a = addressable(a)
+		}
+		pl := c.plan(a.Type())
+		for i := range pl.fields {
+			f := &pl.fields[i]
+			aField, aOK := c.field(a, f)
+			bField, bOK := c.field(b, f)
+			if !aOK || !bOK {
+				continue
+			}
+			c.path = append(c.path, fieldStep(f.name))
+			var err error
+			if !c.isSkipped(f) {
+				err = c.merge(aField, bField)
+			}
+			c.path = c.path[:len(c.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+	case a.Kind() == reflect.Slice && c.isAppendingSlices:
+		value := reflect.New(a.Type()).Elem()
+		if err := c.copy(a, value); err != nil {
+			return err
+		}
+		b.Set(reflect.AppendSlice(b, value))
+		c.changed = append(c.changed, c.at())
+	case a.Kind() == reflect.Map && c.isMergingMaps:
+		if b.IsNil() {
+			b.Set(reflect.MakeMap(b.Type()))
+		}
+		for iter := a.MapRange(); iter.Next(); {
+			value := reflect.New(a.Type().Elem()).Elem()
+			if err := c.copyAt(keyStep(iter.Key()), iter.Value(), value); err != nil {
+				return err
+			} else if existing := b.MapIndex(iter.Key()); existing.IsValid() && isEqual(existing, value) {
+				continue
+			}
+			b.SetMapIndex(iter.Key(), value)
+			c.path = append(c.path, keyStep(iter.Key()))
+			c.changed = append(c.changed, c.at())
+			c.path = c.path[:len(c.path)-1]
+		}
+	case !isEqual(a, b):
+		if err := c.copy(a, b); err != nil {
+			return err
+		}
+		c.changed = append(c.changed, c.at())
+	}
+	return nil
+}
+
+// isEqual returns true if a and b are deeply equal
+func isEqual(a, b reflect.Value) bool {
+	return a.CanInterface() && b.CanInterface() && reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,112 @@
+package sugar
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// path is the location of a value nested inside of the value that is being walked by reflection, eg. `.Items[3].Price`
+type path []step
+
+// step is a single struct field, slice index or map key in a path. Indexes and keys are only formatted when the path
+// is printed, so that walking a value doesn't have to pay for it.
+type step struct {
+	name    string
+	index   int
+	key     reflect.Value
+	isIndex bool
+}
+
+// fieldStep returns the step to a struct field
+func fieldStep(name string) step {
+	return step{name: name}
+}
+
+// indexStep returns the step to a slice or array element
+func indexStep(i int) step {
+	return step{index: i, isIndex: true}
+}
+
+// keyStep returns the step to a map value
+func keyStep(key reflect.Value) step {
+	return step{key: key, isIndex: true}
+}
+
+// String prints the name of the field, the index or the key
+func (s step) String() string {
+	switch {
+	case s.key.IsValid():
+		return fmt.Sprintf("%v", s.key)
+	case s.isIndex:
+		return strconv.Itoa(s.index)
+	}
+	return s.name
+}
+
+// String prints the path like a go expression, eg. `.Items[3].Price`
+func (p path) String() string {
+	var result string
+	for _, s := range p {
+		if s.isIndex {
+			result += "[" + s.String() + "]"
+		} else {
+			result += "." + s.name
+		}
+	}
+	return result
+}
+
+// pointer prints the path as a JSON pointer, eg. `/items/3/price`
+func (p path) pointer() string {
+	var result string
+	for _, s := range p {
+		result += "/" + strings.Replace(strings.Replace(s.String(), "~", "~0", -1), "/", "~1", -1)
+	}
+	return result
+}
+
+// typeName returns the name of the type at the root of a path, eg. `Order` for a *Order
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return t.String()
+}
+
+// pattern matches paths, eg. `Items[*].ID`, where `*` matches any single field, index or key
+type pattern []string
+
+// parsePattern splits a pattern like `Items[*].ID`, or a JSON pointer like `/items/*/id`, into its steps
+func parsePattern(s string) pattern {
+	var result pattern
+	if strings.HasPrefix(s, "/") {
+		for _, token := range strings.Split(s[1:], "/") {
+			result = append(result, strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1))
+		}
+		return result
+	}
+	for _, field := range strings.Split(strings.Replace(s, "[", ".[", -1), ".") {
+		if field = strings.TrimSuffix(strings.TrimPrefix(field, "["), "]"); field != "" {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// match returns true if p is the exact path described by the pattern
+func (pt pattern) match(p path) bool {
+	if len(pt) != len(p) {
+		return false
+	}
+	for i, s := range p {
+		if pt[i] != "*" && pt[i] != s.String() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+//go:build go1.21
+
+package sugar
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler returns a slog.Handler that logs records into log, so that the output of code that logs with log/slog
+// is printed underneath the assertion that it belongs to. Each record is logged as a line with the color of its level
+// and its attributes as fields, and each group is logged as a logger nested underneath it.
+func SlogHandler(log Log) slog.Handler {
+	return &slogHandler{log: log, attrs: [][]slog.Attr{nil}}
+}
+
+// Slog returns a slog.Logger that logs into this Log, so that it can be passed into the code that an assertion
+// tests, eg.
+//
+//	s.Assert("creates a user", func(log sugar.Log) bool {
+//		service := NewService(log.Slog())
+//		...
+//	})
+func (log Log) Slog() *slog.Logger {
+	return slog.New(SlogHandler(log))
+}
+
+// slogHandler is the slog.Handler that is returned by SlogHandler
+type slogHandler struct {
+	log Log
+	// groups are the names of the groups that were opened with WithGroup
+	groups []string
+	// attrs are the attributes that were added with WithAttrs outside of any group, followed by the ones that were
+	// added inside of each group
+	attrs [][]slog.Attr
+}
+
+// Enabled returns true for every level, because lines are hidden when they are printed by the `-sugar.loglevel`
+// flag instead
+func (h *slogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle logs a record as a line, followed by a nested logger for each of its groups
+func (h *slogHandler) Handle(_ context.Context, record slog.Record) error {
+	var attrs []slog.Attr
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	// put the attributes of the record inside of the groups that are open, along with the attributes of each group
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		attrs = append(append([]slog.Attr{}, h.attrs[i+1]...), attrs...)
+		if len(attrs) > 0 {
+			attrs = []slog.Attr{slog.Group(h.groups[i], anys(attrs)...)}
+		}
+	}
+	attrs = append(append([]slog.Attr{}, h.attrs[0]...), attrs...)
+
+	// log the line and its groups in one call, so that lines from other goroutines can't end up between them
+	var groups []interface{}
+	for _, group := range groupsOf(attrs) {
+		groups = append(groups, groupLogger(group))
+	}
+	h.log(newEntry(slogLevel(record.Level), record.Message, nil).withAttrs(attrs), groups...)
+	return nil
+}
+
+// WithAttrs returns a handler that adds attributes to every record, inside of the groups that are open
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	handler := h.clone()
+	last := len(handler.attrs) - 1
+	handler.attrs[last] = append(append([]slog.Attr{}, handler.attrs[last]...), attrs...)
+	return handler
+}
+
+// WithGroup returns a handler that puts the attributes that are added after it inside of a group
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := h.clone()
+	handler.groups = append(handler.groups, name)
+	handler.attrs = append(handler.attrs, nil)
+	return handler
+}
+
+// clone copies the handler, so that the handler that it was copied from isn't changed by WithAttrs or WithGroup
+func (h *slogHandler) clone() *slogHandler {
+	return &slogHandler{
+		log:    h.log,
+		groups: append([]string{}, h.groups...),
+		attrs:  append([][]slog.Attr{}, h.attrs...),
+	}
+}
+
+// withAttrs adds the attributes that aren't groups to a line as fields
+func (e entry) withAttrs(attrs []slog.Attr) entry {
+	for _, attr := range flatten(attrs) {
+		if attr.Value.Kind() != slog.KindGroup {
+			e.fields = append(e.fields, field{attr.Key, attr.Value.Any()})
+		}
+	}
+	return e
+}
+
+// groupLogger returns a logger with the name of a group and its attributes on the first line, and a nested logger
+// for each of the groups inside of it
+func groupLogger(group slog.Attr) Logger {
+	l := NewLogger()
+	attrs := group.Value.Group()
+	l.Log(entry{message: group.Key}.withAttrs(attrs))
+	for _, nested := range groupsOf(attrs) {
+		l.Log(groupLogger(nested))
+	}
+	return l
+}
+
+// groupsOf returns the attributes that are groups with at least one attribute in them
+func groupsOf(attrs []slog.Attr) []slog.Attr {
+	var groups []slog.Attr
+	for _, attr := range flatten(attrs) {
+		if attr.Value.Kind() == slog.KindGroup && len(attr.Value.Group()) > 0 {
+			groups = append(groups, attr)
+		}
+	}
+	return groups
+}
+
+// flatten resolves each attribute's value, drops empty attributes and inlines the attributes of groups without a key,
+// the way that slog.Handler says they should be handled
+func flatten(attrs []slog.Attr) []slog.Attr {
+	var flattened []slog.Attr
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			continue
+		} else if attr.Value.Kind() == slog.KindGroup && attr.Key == "" {
+			flattened = append(flattened, flatten(attr.Value.Group())...)
+		} else {
+			flattened = append(flattened, attr)
+		}
+	}
+	return flattened
+}
+
+// anys converts attributes into the arguments of slog.Group
+func anys(attrs []slog.Attr) []interface{} {
+	args := make([]interface{}, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	return args
+}
+
+// slogLevel returns the Level that a slog.Level falls into
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
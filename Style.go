@@ -0,0 +1,154 @@
+package sugar
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Style is the way that Compare renders the differences that it finds. It can be chosen for every test with the
+// `-sugar.diff=nested|side-by-side|compact` flag, for a single Logger with SetStyle or for a single call with UseStyle.
+type Style int
+
+const (
+	// DefaultStyle uses the style that is set by the `-sugar.diff` flag, which is NestedStyle unless it is set
+	DefaultStyle Style = iota
+
+	// NestedStyle logs each difference underneath its path, with the expected and found values nested underneath it
+	NestedStyle
+
+	// SideBySideStyle logs each difference on a single row, with its path and the expected and found values in
+	// aligned columns that are sized to the width of the terminal
+	SideBySideStyle
+
+	// CompactStyle logs each difference on a single plain line, which is easier to read in CI logs
+	CompactStyle
+)
+
+// styleNames are the names of the styles, as they are passed into the `-sugar.diff` flag
+var styleNames = map[Style]string{
+	DefaultStyle:    "default",
+	NestedStyle:     "nested",
+	SideBySideStyle: "side-by-side",
+	CompactStyle:    "compact",
+}
+
+// defaultStyle is the style that is set by the `-sugar.diff` flag
+var defaultStyle = NestedStyle
+
+// minColumnWidth is the narrowest that the columns of SideBySideStyle get, even in a narrow terminal
+const minColumnWidth = 8
+
+func init() {
+	flag.Var(&defaultStyle, "sugar.diff", "the style that sugar renders differences in: nested, side-by-side or compact")
+}
+
+// String returns the name of the style
+func (s Style) String() string {
+	return styleNames[s]
+}
+
+// Set sets the style by its name, so that a Style can be used as a flag
+func (s *Style) Set(name string) error {
+	for style, styleName := range styleNames {
+		if style != DefaultStyle && styleName == name {
+			*s = style
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a style, use nested, side-by-side or compact", name)
+}
+
+// UseStyle makes Compare render the differences that it finds in a style
+func UseStyle(style Style) CompareOption {
+	return func(c *comparer) {
+		c.style = style
+	}
+}
+
+// terminalWidth returns the width of the terminal from the COLUMNS environment variable. `go test` pipes the output
+// of tests, so the terminal can't be asked for its size directly.
+func terminalWidth() int {
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 120
+}
+
+// renderSideBySide logs every difference on a row, with its path and the expected and found values in columns
+func renderSideBySide(log Log, root string, differences []Difference) {
+	cells := make([][3]string, len(differences))
+	pathWidth := len("path")
+	for i, d := range differences {
+		cells[i] = [3]string{root + d.Path, cell(d.Expected), cell(d.Found)}
+		switch d.Kind {
+		case Added:
+			cells[i][1] = "<none>"
+		case Removed:
+			cells[i][2] = "<none>"
+		}
+		if width := utf8.RuneCountInString(cells[i][0]); width > pathWidth {
+			pathWidth = width
+		}
+	}
+
+	// leave room for the tags that the logger prints in front of each row and for the gaps between the columns
+	width := terminalWidth() - 16
+	if pathWidth > width/2 {
+		pathWidth = width / 2
+	}
+	if pathWidth < minColumnWidth {
+		pathWidth = minColumnWidth
+	}
+	valueWidth := (width - pathWidth) / 2
+	if valueWidth < minColumnWidth {
+		valueWidth = minColumnWidth
+	}
+
+	rows := []string{
+		countDifferences(len(differences)),
+		grayColor(pad("path", pathWidth) + "  " + pad("expected", valueWidth) + "  found"),
+	}
+	for _, row := range cells {
+		// the end of a path is the most useful part of it, so long paths are cut from the front
+		if utf8.RuneCountInString(row[0]) > pathWidth {
+			row[0] = "…" + string([]rune(row[0])[utf8.RuneCountInString(row[0])-pathWidth+1:])
+		}
+		rows = append(rows, pad(row[0], pathWidth)+"  "+redColor(pad(row[1], valueWidth))+"  "+
+			greenColor(truncate(row[2], valueWidth)))
+	}
+	log(strings.Join(rows, "\n"))
+}
+
+// renderCompact logs every difference on a plain line of its own
+func renderCompact(log Log, root string, differences []Difference) {
+	lines := []string{countDifferences(len(differences))}
+	for _, d := range differences {
+		lines = append(lines, root+d.String())
+	}
+	log(strings.Join(lines, "\n"))
+}
+
+// cell prints a value so that it fits on a single row
+func cell(v interface{}) string {
+	return strings.Replace(fmt.Sprintf("%+v", v), "\n", `\n`, -1)
+}
+
+// truncate cuts s down to width runes, marking where it was cut
+func truncate(s string, width int) string {
+	if width < 1 {
+		return ""
+	} else if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// pad truncates s, or pads it with spaces, to exactly width runes
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
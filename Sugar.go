@@ -187,10 +187,10 @@ func New(t *testing.T, outs ...io.Writer) Sugar {
 func (s *sugar) Assert(name string, isPassed Test) Sugar {
 	startTime := time.Now()
 	l := NewLogger()
-	if <-recoverFromPanic(isPassed, l.Log) {
+	if <-recoverFromPanic(isPassed, l) && !isFailed(l) {
 		if testing.Verbose() {
 			fmt.Fprintf(s.out, "%s	%20s	%s\n", greenColor("PASS"), cyanColor(time.Now().Sub(startTime)), name)
-			fmt.Fprint(s.out, l)
+			fmt.Fprint(s.out, visible(l))
 		}
 	} else {
 		fmt.Fprintf(s.out, "%s	%20s	%s\n", redColor("FAIL"), cyanColor(time.Now().Sub(startTime)), name)
@@ -204,10 +204,10 @@ func (s *sugar) Assert(name string, isPassed Test) Sugar {
 func (s *sugar) Warn(name string, isPassed Test) Sugar {
 	startTime := time.Now()
 	l := NewLogger()
-	if <-recoverFromPanic(isPassed, l.Log) {
+	if <-recoverFromPanic(isPassed, l) && !isFailed(l) {
 		if testing.Verbose() {
 			fmt.Fprintf(s.out, "%s	%20s	%s\n", greenColor("PASS"), cyanColor(time.Now().Sub(startTime)), name)
-			fmt.Fprint(s.out, l)
+			fmt.Fprint(s.out, visible(l))
 		}
 	} else {
 		fmt.Fprintf(s.out, "%s	%20s	%s\n", yellowColor("WARN"), cyanColor(time.Now().Sub(startTime)), name)
@@ -220,10 +220,10 @@ func (s *sugar) Warn(name string, isPassed Test) Sugar {
 func (s *sugar) Must(name string, isPassed Test) Sugar {
 	startTime := time.Now()
 	l := NewLogger()
-	if <-recoverFromPanic(isPassed, l.Log) {
+	if <-recoverFromPanic(isPassed, l) && !isFailed(l) {
 		if testing.Verbose() {
 			fmt.Fprintf(s.out, "%s	%20s	%s\n", greenColor("PASS"), cyanColor(time.Now().Sub(startTime)), name)
-			fmt.Fprint(s.out, l)
+			fmt.Fprint(s.out, visible(l))
 		}
 	} else {
 		fmt.Fprintf(s.out, "%s	%20s	%s\n", redColor("FATAL"), cyanColor(time.Now().Sub(startTime)), name)
@@ -251,9 +251,10 @@ func (s *sugar) IsFailed() bool {
 	return s.t.Failed()
 }
 
-// recovers from panics and logs the panic
-func recoverFromPanic(isPassed Test, log Log) chan bool {
+// recovers from panics and logs the panic, or logs the expression that made the test fail
+func recoverFromPanic(isPassed Test, l Logger) chan bool {
 	isPassedChannel := make(chan bool)
+	log := Log(l.Log)
 	go func() {
 		defer func() {
 			err := recover()
@@ -263,7 +264,11 @@ func recoverFromPanic(isPassed Test, log Log) chan bool {
 				isPassedChannel <- false
 			}
 		}()
-		isPassedChannel <- isPassed(log)
+		result := isPassed(log)
+		if !result {
+			logReturn(isPassed, l)
+		}
+		isPassedChannel <- result
 	}()
 	return isPassedChannel
 }
@@ -0,0 +1,237 @@
+package sugar
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// textDiffLength is how long a single line string has to be before its differences are rendered as a diff
+const textDiffLength = 80
+
+// maxEditCells limits how much memory editScript can use, so that huge texts that are completely different don't
+// stall a test
+const maxEditCells = 1 << 20
+
+// edit is a single operation that turns one text into another. op is ' ' to keep text, '-' to remove it and '+' to
+// add it.
+type edit struct {
+	op   byte
+	text string
+}
+
+// textLines splits a string or []byte difference into the lines that should be diffed, and returns false if the
+// values are short enough to print on their own. Binary data is split into the lines of a hexdump.
+func textLines(expected, found interface{}) ([]string, []string, bool) {
+	switch e := expected.(type) {
+	case string:
+		f, ok := found.(string)
+		if !ok || (len(e) < textDiffLength && len(f) < textDiffLength && !strings.Contains(e+f, "\n")) {
+			return nil, nil, false
+		}
+		return strings.Split(e, "\n"), strings.Split(f, "\n"), true
+	case []byte:
+		f, ok := found.([]byte)
+		if !ok {
+			return nil, nil, false
+		} else if isBinary(e) || isBinary(f) {
+			return dumpLines(e), dumpLines(f), true
+		}
+		return textLines(string(e), string(f))
+	}
+	return nil, nil, false
+}
+
+// isBinary returns true if b can't be printed as text
+func isBinary(b []byte) bool {
+	if !utf8.Valid(b) {
+		return true
+	}
+	for _, r := range string(b) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpLines returns the lines of a hexdump of b
+func dumpLines(b []byte) []string {
+	return strings.Split(strings.TrimSuffix(hex.Dump(b), "\n"), "\n")
+}
+
+// unifiedDiff returns the hunks of a colorized unified diff between two texts, with context unchanged lines around
+// each change. Lines that were changed highlight the words, or the characters, that are different.
+func unifiedDiff(a, b []string, context int, isByCharacter bool) []string {
+	edits := editScript(a, b)
+	var hunks []string
+	aLine, bLine := 1, 1
+	for start := 0; start < len(edits); {
+		// find the next change, then keep going until there's a gap between changes that's too big to bridge
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first + 1; i < len(edits) && i-last <= 2*context; i++ {
+			if edits[i].op != ' ' {
+				last = i
+			}
+		}
+		from, to := first-context, last+context+1
+		if from < start {
+			from = start
+		}
+		if to > len(edits) {
+			to = len(edits)
+		}
+
+		// skip the unchanged lines that come before the hunk, then count the lines inside of it
+		aLine, bLine = aLine+from-start, bLine+from-start
+		aCount, bCount := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		hunk := []string{cyanColor(fmt.Sprintf("@@ -%d,%d +%d,%d @@", aLine, aCount, bLine, bCount))}
+		hunk = append(hunk, renderHunk(edits[from:to], isByCharacter)...)
+		hunks = append(hunks, strings.Join(hunk, "\n"))
+		aLine, bLine = aLine+aCount, bLine+bCount
+		start = to
+	}
+	return hunks
+}
+
+// renderHunk colors the lines of a hunk, pairing up removed and added lines so that the parts of them that changed
+// can be highlighted
+func renderHunk(edits []edit, isByCharacter bool) []string {
+	var lines []string
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			lines = append(lines, grayColor(" "+edits[i].text))
+			i++
+			continue
+		}
+		var removed, added []string
+		for ; i < len(edits) && edits[i].op != ' '; i++ {
+			if edits[i].op == '-' {
+				removed = append(removed, edits[i].text)
+			} else {
+				added = append(added, edits[i].text)
+			}
+		}
+		for j := range removed {
+			if j < len(added) {
+				removed[j], added[j] = highlight(removed[j], added[j], isByCharacter)
+			} else {
+				removed[j] = redColor("-" + removed[j])
+			}
+		}
+		for j := range added {
+			if j >= len(removed) {
+				added[j] = greenColor("+" + added[j])
+			}
+		}
+		lines = append(append(lines, removed...), added...)
+	}
+	return lines
+}
+
+// highlight colors a removed line and the line that replaced it, highlighting the words or characters that changed
+func highlight(a, b string, isByCharacter bool) (string, string) {
+	removed, added := red+"-", green+"+"
+	for _, e := range editScript(tokenize(a, isByCharacter), tokenize(b, isByCharacter)) {
+		switch e.op {
+		case '-':
+			removed += redInverse + e.text + red
+		case '+':
+			added += greenInverse + e.text + green
+		default:
+			removed, added = removed+e.text, added+e.text
+		}
+	}
+	return removed + reset, added + reset
+}
+
+// tokenize splits a line into words and the characters between them, or into characters
+func tokenize(s string, isByCharacter bool) []string {
+	var tokens []string
+	isWord := func(r rune) bool {
+		return !isByCharacter && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	}
+	for i, r := range s {
+		if len(tokens) > 0 && isWord(r) {
+			last := tokens[len(tokens)-1]
+			if lastRune, _ := utf8.DecodeLastRuneInString(last); isWord(lastRune) {
+				tokens[len(tokens)-1] = last + string(r)
+				continue
+			}
+		}
+		tokens = append(tokens, s[i:i+utf8.RuneLen(r)])
+	}
+	return tokens
+}
+
+// editScript returns the edits that turn a into b, keeping the longest common subsequence of the two
+func editScript(a, b []string) []edit {
+	var prefix, suffix []edit
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, edit{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]edit{{' ', a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	edits := prefix
+	if (len(a)+1)*(len(b)+1) > maxEditCells {
+		// it's too big to find what the two have in common, so replace all of a with all of b
+		for _, s := range a {
+			edits = append(edits, edit{'-', s})
+		}
+		for _, s := range b {
+			edits = append(edits, edit{'+', s})
+		}
+		return append(edits, suffix...)
+	}
+
+	// lengths[i*width+j] is the length of the longest common subsequence of a[i:] and b[j:]
+	width := len(b) + 1
+	lengths := make([]int, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			} else if lengths[(i+1)*width+j] >= lengths[i*width+j+1] {
+				lengths[i*width+j] = lengths[(i+1)*width+j]
+			} else {
+				lengths[i*width+j] = lengths[i*width+j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lengths[(i+1)*width+j] >= lengths[i*width+j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	return append(edits, suffix...)
+}
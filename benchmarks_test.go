@@ -0,0 +1,87 @@
+package sugar_test
+
+import (
+	"fmt"
+	"github.com/marksalpeter/sugar"
+	"testing"
+	"time"
+)
+
+type Fixture struct {
+	ID         uint
+	Name       string
+	CreatedAt  time.Time
+	Tags       []string
+	Attributes map[string]string
+	Order      *Order
+	Counters   []Counter
+}
+
+func newFixture() *Fixture {
+	fixture := Fixture{
+		ID:         1,
+		Name:       "fixture",
+		CreatedAt:  time.Unix(1468368000, 0),
+		Attributes: make(map[string]string),
+		Order:      &Order{},
+	}
+	for i := 0; i < 100; i++ {
+		fixture.Tags = append(fixture.Tags, fmt.Sprintf("tag %d", i))
+		fixture.Attributes[fmt.Sprintf("key %d", i)] = fmt.Sprintf("value %d", i)
+		fixture.Order.Items = append(fixture.Order.Items, Item{Price: float64(i), Sizes: [3]int{i, i, i}})
+		fixture.Counters = append(fixture.Counters, Counter{Name: fmt.Sprintf("counter %d", i), Conn: &Conn{ID: uint(i)}})
+	}
+	return &fixture
+}
+
+func BenchmarkCopy(b *testing.B) {
+	fixture := newFixture()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var copied Fixture
+		if err := sugar.Copy(fixture, &copied); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyRecompilingPlans measures what the plan cache saves, by compiling every plan again on every copy. It
+// isn't a benchmark of the walker that came before plans.
+func BenchmarkCopyRecompilingPlans(b *testing.B) {
+	fixture := newFixture()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var copied Fixture
+		if err := sugar.CopyRecompilingPlans(fixture, &copied); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopyParallel(b *testing.B) {
+	fixture := newFixture()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var copied Fixture
+			if err := sugar.Copy(fixture, &copied); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCopyMapped(b *testing.B) {
+	fixture := newFixture()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var copied Fixture
+		if _, err := sugar.CopyMapped(fixture, &copied); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package sugar
+
+// CopyRecompilingPlans copies a into b like Copy does, except that it compiles a new plan every time it needs one
+// instead of using the cached plans, so that the benchmarks can measure what the cache saves. It can't stand in for the
+// walker that came before plans, because compiling a plan also works out isValue for the whole type, which that walker
+// never did.
+func CopyRecompilingPlans(a, b interface{}, opts ...CopyOption) error {
+	return Copy(a, b, append(opts, func(c *copier) {
+		c.isUncached = true
+	})...)
+}
+
+// CallerString prints a location the same way that log lines are tagged with it, so that the `-sugar.relative` flag
+// can be tested on files outside of the module root
+func CallerString(file string, line int) string {
+	return caller{file: file, line: line}.String()
+}
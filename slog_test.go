@@ -0,0 +1,60 @@
+//go:build go1.21
+
+package sugar_test
+
+import (
+	"fmt"
+	"github.com/marksalpeter/sugar"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Assert("slog records are logged with their level, attributes and groups", func(log sugar.Log) bool {
+		logger := sugar.NewLogger()
+		_, _, line, _ := runtime.Caller(0)
+		slogger := sugar.Log(logger.Log).Slog().With("service", "users")
+		slogger.Warn("slow request", "ms", 250, slog.Group("request", "method", "GET", slog.Group("headers", "accept", "json")))
+		slogger.WithGroup("db").With("table", "users").Error("insert failed", "id", 7)
+		slogger.Debug("empty group", slog.Group("nothing"))
+		output := logger.String()
+		for _, expected := range []string{
+			"WARN  slow request", "=users", "=250", "request", "=GET", "headers", "=json",
+			"ERROR insert failed", "db", "=7",
+			"DEBUG empty group",
+			fmt.Sprintf("[slog_test.go:%d]", line+2),
+		} {
+			if !strings.Contains(output, expected) {
+				log("expected %q in the output", expected)
+				log(output)
+				return false
+			}
+		}
+		return !strings.Contains(output, "nothing")
+	})
+
+	s.Assert("slog records are logged in the same call as their groups, so other goroutines can't split them up", func(log sugar.Log) bool {
+		var calls [][]interface{}
+		slogger := sugar.Log(func(s interface{}, args ...interface{}) {
+			calls = append(calls, append([]interface{}{s}, args...))
+		}).With("service", "users").Slog()
+		slogger.Info("created", slog.Group("request", "method", "GET"), slog.Group("response", "status", 201))
+		if len(calls) != 1 || len(calls[0]) != 3 {
+			log("expected the record and both of its groups in one call: %+v", calls)
+			return false
+		}
+		for i, expected := range []string{"created", "request", "response"} {
+			if !strings.Contains(fmt.Sprint(calls[0][i]), expected) {
+				log("expected %q in %+v", expected, calls[0][i])
+				return false
+			}
+		}
+		return true
+	})
+
+}
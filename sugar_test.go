@@ -1,8 +1,19 @@
 package sugar_test
 
 import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/marksalpeter/sugar"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type SubStruct struct {
@@ -16,6 +27,78 @@ type Struct struct {
 	*SubStruct
 }
 
+type Node struct {
+	Name     string
+	Parent   *Node
+	Children []*Node
+	Sibling  *Node
+}
+
+type DTO struct {
+	Name      string
+	Count     int64
+	Body      string
+	CreatedAt *time.Time
+	Tags      []string
+	Secret    string
+	Ident     int `sugar:"ID"`
+}
+
+type Model struct {
+	ID        uint
+	Tags      []string
+	CreatedAt time.Time
+	Body      []byte
+	Count     int32
+	Name      *string
+}
+
+type Item struct {
+	Price    float64
+	Discount complex128
+	Sizes    [3]int
+	Extra    interface{}
+}
+
+type Handler struct {
+	Callback func()
+}
+
+type Order struct {
+	Items    []Item
+	Handlers []Handler
+}
+
+type Counter struct {
+	mutex sync.Mutex
+	count int
+	Name  string
+	Conn  *Conn
+	Cache map[string]*SubStruct `json:"-"`
+}
+
+func (c *Counter) Count() int {
+	return c.count
+}
+
+type Conn struct {
+	ID     uint
+	Clones int
+}
+
+func (c *Conn) Clone() interface{} {
+	return &Conn{ID: c.ID, Clones: c.Clones + 1}
+}
+
+type Row struct {
+	ID        uint
+	Name      string
+	Score     float64
+	UpdatedAt time.Time
+	Items     []Item
+	Version   int `sugar:"ignore"`
+}
+
 func TestStruct(t *testing.T) {
 
 	s := sugar.New(t)
@@ -56,6 +139,147 @@ func TestStruct(t *testing.T) {
 
 }
 
+func logFromHelper(log sugar.Log) {
+	log.Helper()
+	log("logged from a helper")
+}
+
+func TestLogger(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Assert("log lines are tagged with the file and line outside of sugar and its helpers", func(log sugar.Log) bool {
+		logger := sugar.NewLogger()
+		_, _, line, _ := runtime.Caller(0)
+		logFromHelper(logger.Log)
+		logger.Compare(1, 2)
+		for _, expected := range []string{fmt.Sprintf("[sugar_test.go:%d]", line+1), fmt.Sprintf("[sugar_test.go:%d]", line+2)} {
+			if !strings.Contains(logger.String(), expected) {
+				log("expected %q in the output", expected)
+				log(logger)
+				return false
+			}
+		}
+
+		return true
+	})
+
+	s.Assert("log lines are tagged relative to the module root with -sugar.relative", func(log sugar.Log) bool {
+		root, err := os.MkdirTemp("", "sugar")
+		if err != nil {
+			log(err)
+			return false
+		}
+		defer os.RemoveAll(root)
+		if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0644); err != nil {
+			log(err)
+			return false
+		}
+		file := filepath.Join(root, "internal", "users", "users_test.go")
+		if !log.Compare("users_test.go:12", sugar.CallerString(file, 12)) {
+			return false
+		}
+
+		flag.Set("sugar.relative", "true")
+		defer flag.Set("sugar.relative", "false")
+		return log.Compare("internal/users/users_test.go:12", sugar.CallerString(file, 12))
+	})
+
+	s.Assert("loggers can be used from more than one goroutine and keep the order of each goroutine's lines", func(log sugar.Log) bool {
+		flag.Set("sugar.goroutines", "true")
+		defer flag.Set("sugar.goroutines", "false")
+
+		logger := sugar.NewLogger()
+		var wait sync.WaitGroup
+		for worker := 0; worker < 8; worker++ {
+			wait.Add(1)
+			go func(worker int) {
+				defer wait.Done()
+				nestedLogger := sugar.NewLogger()
+				for i := 0; i < 20; i++ {
+					logger.Log("worker %d line %d.", worker, i)
+					nestedLogger.Log("nested worker %d line %d.", worker, i)
+					logger.Expect(i).To(sugar.BeNumerically(">=", 0))
+				}
+				logger.Log(nestedLogger)
+			}(worker)
+		}
+		wait.Wait()
+
+		output := logger.String()
+		for worker := 0; worker < 8; worker++ {
+			for i := 1; i < 20; i++ {
+				previous := strings.Index(output, fmt.Sprintf("worker %d line %d.", worker, i-1))
+				if current := strings.Index(output, fmt.Sprintf("worker %d line %d.", worker, i)); previous < 0 || current < previous {
+					log("expected worker %d's lines to be in order", worker)
+					log(output)
+					return false
+				}
+			}
+		}
+		return strings.Count(output, "nested worker") == 160 && strings.Contains(output, " goroutine ")
+	})
+
+	s.Assert("lines can be logged with a level and with fields that line up", func(log sugar.Log) bool {
+		logger := sugar.NewLogger()
+		logger.Debug("connecting to %s", "db")
+		logger.Error("failed")
+		logger.With("user", 7).With("attempt", 2).Info("created")
+		logger.With("user", 12).Warn("a longer message")
+		output := logger.String()
+		for _, expected := range []string{"DEBUG connecting to db", "ERROR failed", "INFO  created", "WARN  a longer message", "=7", "=12", "=2"} {
+			if !strings.Contains(output, expected) {
+				log("expected %q in the output", expected)
+				log(output)
+				return false
+			}
+		}
+		lines := strings.Split(output, "\n")
+		return strings.Index(lines[2], "user") == strings.Index(lines[3], "user")
+	})
+
+	s.Assert("expectations that are logged with fields still fail the test", func(log sugar.Log) bool {
+		var output bytes.Buffer
+		main := sugar.New(nil, &output)
+		main.Assert("a failing expectation with fields", func(log sugar.Log) bool {
+			log.With("user", 7).Expect(1).To(sugar.Equal(2))
+			log.With("user", 7).Compare(1, 3)
+			return true
+		})
+		for _, expected := range []string{"FAIL", "expected 1.(int) to equal 2", "=7", "1 difference"} {
+			if !strings.Contains(output.String(), expected) {
+				log("expected %q in the output", expected)
+				log(output.String())
+				return false
+			}
+		}
+		return true
+	})
+
+	s.Assert("debug lines are hidden for passing tests by -sugar.loglevel, but not for failing ones", func(log sugar.Log) bool {
+		flag.Set("sugar.loglevel", "info")
+		defer flag.Set("sugar.loglevel", "debug")
+
+		var output bytes.Buffer
+		main := sugar.New(nil, &output)
+		main.Assert("a passing test", func(log sugar.Log) bool {
+			log.Debug("hidden debug line")
+			log.Info("shown info line")
+			return true
+		})
+		main.Assert("a failing test", func(log sugar.Log) bool {
+			log.Debug("failing debug line")
+			return false
+		})
+		if testing.Verbose() && (strings.Contains(output.String(), "hidden debug line") || !strings.Contains(output.String(), "shown info line")) {
+			log(output.String())
+			return false
+		}
+		return strings.Contains(output.String(), "failing debug line") && flag.Set("sugar.loglevel", "trace") != nil
+	})
+
+}
+
 func TestCopy(t *testing.T) {
 
 	s := sugar.New(t)
@@ -84,4 +308,775 @@ func TestCopy(t *testing.T) {
 		return log.Compare(a, b)
 	})
 
+	s.Assert("copy terminates on cycles and keeps shared pointers shared", func(log sugar.Log) bool {
+		root := &Node{Name: "root"}
+		left := &Node{Name: "left", Parent: root}
+		right := &Node{Name: "right", Parent: root, Sibling: left}
+		left.Sibling = right
+		root.Children = []*Node{left, right}
+
+		var b Node
+		if err := sugar.Copy(root, &b); err != nil {
+			log(err)
+			return false
+		} else if len(b.Children) != 2 {
+			log("expected 2 children, found %d", len(b.Children))
+			return false
+		}
+		bLeft, bRight := b.Children[0], b.Children[1]
+		if bLeft == left || bRight == right {
+			log("children were not copied")
+			return false
+		} else if bLeft.Parent != &b || bRight.Parent != &b {
+			log("parent pointers do not point at the copy of the root")
+			return false
+		} else if bLeft.Sibling != bRight || bRight.Sibling != bLeft {
+			log("sibling pointers are not shared in the copy")
+			return false
+		}
+		return bLeft.Name == "left" && bRight.Name == "right"
+	})
+
+	s.Assert("copy mapped matches fields by name and tag and converts between compatible kinds", func(log sugar.Log) bool {
+		createdAt := time.Unix(1468368000, 0)
+		dto := DTO{
+			Name:      "name",
+			Count:     7,
+			Body:      "body",
+			CreatedAt: &createdAt,
+			Tags:      []string{"a", "b"},
+			Secret:    "secret",
+			Ident:     3,
+		}
+		var model Model
+		unmapped, err := sugar.CopyMapped(&dto, &model)
+		if err != nil {
+			log(err)
+			return false
+		}
+		name := "name"
+		return log.Compare([]string{"DTO.Secret"}, unmapped) && log.Compare(Model{
+			ID:        3,
+			Tags:      []string{"a", "b"},
+			CreatedAt: createdAt,
+			Body:      []byte("body"),
+			Count:     7,
+			Name:      &name,
+		}, model)
+	})
+
+	s.Assert("copy mapped doesn't map fields that compare ignores, or names that more than one field maps to", func(log sugar.Log) bool {
+		type Ignored struct {
+			Version  int `sugar:"ignore"`
+			Revision int `sugar:"-"`
+			Name     string
+		}
+		type Plain struct {
+			Version  int
+			Revision int
+			Name     string
+		}
+		type Ambiguous struct {
+			Name  string
+			Title string `sugar:"Name"`
+		}
+		var plain Plain
+		unmapped, err := sugar.CopyMapped(Ignored{Version: 1, Revision: 2, Name: "a"}, &plain)
+		if err != nil {
+			log(err)
+			return false
+		} else if !log.Compare([]string(nil), unmapped) || !log.Compare(Plain{Name: "a"}, plain) {
+			return false
+		}
+
+		var ignored Ignored
+		if unmapped, err = sugar.CopyMapped(Plain{Version: 1, Revision: 2, Name: "b"}, &ignored); err != nil {
+			log(err)
+			return false
+		} else if !log.Compare([]string{"Plain.Version", "Plain.Revision"}, unmapped) || !log.Compare(Ignored{Name: "b"}, ignored) {
+			return false
+		}
+
+		var ambiguous Ambiguous
+		if unmapped, err = sugar.CopyMapped(Plain{Name: "c"}, &ambiguous); err != nil {
+			log(err)
+			return false
+		}
+		return log.Compare([]string{"Plain.Version", "Plain.Revision", "Plain.Name"}, unmapped) && log.Compare(Ambiguous{}, ambiguous)
+	})
+
+	s.Assert("copy mapped doesn't truncate numbers that don't fit", func(log sugar.Log) bool {
+		type Src struct {
+			N, M int64
+			F, G float64
+		}
+		type Dst struct {
+			N, M int8
+			F, G uint
+		}
+		var dst Dst
+		unmapped, err := sugar.CopyMapped(Src{N: 300, M: -3, F: 1.9, G: 2}, &dst)
+		if err != nil {
+			log(err)
+			return false
+		}
+		return log.Compare([]string{"Src.N", "Src.F"}, unmapped) && log.Compare(Dst{M: -3, G: 2}, dst)
+	})
+
+	s.Assert("copy copies floats, complex numbers, arrays and interfaces by value", func(log sugar.Log) bool {
+		a := Order{Items: []Item{{
+			Price:    1.5,
+			Discount: complex(1, 2),
+			Sizes:    [3]int{1, 2, 3},
+			Extra:    &SubStruct{ID: 4},
+		}}}
+		var b Order
+		if err := sugar.Copy(a, &b); err != nil {
+			log(err)
+			return false
+		} else if b.Items[0].Extra == a.Items[0].Extra {
+			log("the pointer inside of the interface was not copied")
+			return false
+		}
+		return log.Compare(a, b)
+	})
+
+	s.Assert("copy returns errors that include the path of the value that could not be copied", func(log sugar.Log) bool {
+		a := Order{Handlers: []Handler{{}, {Callback: func() {}}}}
+		var b Order
+		err := sugar.Copy(&a, &b, sugar.RejectChansAndFuncs())
+		if err == nil {
+			log("expected an error")
+			return false
+		}
+		return log.Compare("Order.Handlers[1].Callback: tried to copy a func()", err.Error())
+	})
+
+	s.Assert("copy options skip fields, limit depth, copy unexported fields and use cloners", func(log sugar.Log) bool {
+		a := Counter{
+			count: 3,
+			Name:  "counter",
+			Conn:  &Conn{ID: 1},
+			Cache: map[string]*SubStruct{"a": {ID: 2}},
+		}
+
+		var b Counter
+		if err := sugar.Copy(&a, &b); err != nil {
+			log(err)
+			return false
+		} else if b.Count() != 0 || b.Conn.Clones != 1 || b.Cache["a"] == a.Cache["a"] {
+			log("expected unexported fields to be skipped and conns to be cloned: %+v", &b)
+			return false
+		}
+
+		var c Counter
+		if err := sugar.Copy(&a, &c, sugar.CopyUnexported(), sugar.SkipFields("Conn"), sugar.SkipTagged("json", "-")); err != nil {
+			log(err)
+			return false
+		} else if c.Count() != 3 || c.Conn != nil || c.Cache != nil || c.Name != "counter" {
+			log("expected unexported fields to be copied and conns and caches to be skipped: %+v", &c)
+			return false
+		}
+
+		var d Counter
+		if err := sugar.Copy(&a, &d, sugar.MaxDepth(1), sugar.SkipPaths("Conn")); err != nil {
+			log(err)
+			return false
+		} else if d.Cache["a"] != a.Cache["a"] || d.Conn != nil {
+			log("expected values below depth 1 to be shared and conns to be skipped: %+v", &d)
+			return false
+		}
+		return true
+	})
+
+	s.Assert("copy skips fields of structs that only hold values", func(log sugar.Log) bool {
+		type Tagged struct {
+			ID     uint
+			Secret string `json:"-"`
+		}
+		var b SubStruct
+		if err := sugar.Copy(SubStruct{ID: 1, Is: true}, &b, sugar.SkipFields("Is")); err != nil {
+			log(err)
+			return false
+		} else if !log.Compare(SubStruct{ID: 1}, b) {
+			return false
+		}
+
+		var c [2]Tagged
+		if err := sugar.Copy([2]Tagged{{1, "a"}, {2, "b"}}, &c, sugar.SkipTagged("json", "-")); err != nil {
+			log(err)
+			return false
+		} else if !log.Compare([2]Tagged{{ID: 1}, {ID: 2}}, c) {
+			return false
+		}
+
+		var d Row
+		updatedAt := time.Unix(1468368000, 0)
+		if err := sugar.Copy(Row{ID: 3, Name: "row", UpdatedAt: updatedAt}, &d, sugar.SkipPaths("Name")); err != nil {
+			log(err)
+			return false
+		}
+		return log.Compare(Row{ID: 3, UpdatedAt: updatedAt}, d)
+	})
+
+	s.Assert("copy replaces maps instead of adding to them", func(log sugar.Log) bool {
+		b := Counter{Cache: map[string]*SubStruct{"z": {ID: 9}}}
+		if err := sugar.Copy(&Counter{Cache: map[string]*SubStruct{"a": {ID: 1}}}, &b); err != nil {
+			log(err)
+			return false
+		} else if !log.Compare(map[string]*SubStruct{"a": {ID: 1}}, b.Cache) {
+			return false
+		} else if err := sugar.Copy(&Counter{}, &b); err != nil {
+			log(err)
+			return false
+		}
+		return b.Cache == nil
+	})
+
+}
+
+func TestMerge(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Assert("merge copies the fields that are not zero and returns the paths that it changed", func(log sugar.Log) bool {
+		createdAt := time.Unix(1468368000, 0)
+		name := ""
+		fixture := Model{ID: 1, Tags: []string{"a"}, Body: []byte("body"), Count: 2, Name: &name}
+		other := "other"
+		changed, err := sugar.Merge(&fixture, Model{ID: 1, Tags: []string{"b"}, CreatedAt: createdAt, Name: &other})
+		if err != nil {
+			log(err)
+			return false
+		}
+		return log.Compare([]string{"Model.Tags", "Model.CreatedAt", "Model.Name"}, changed) && log.Compare(Model{
+			ID:        1,
+			Tags:      []string{"b"},
+			CreatedAt: createdAt,
+			Body:      []byte("body"),
+			Count:     2,
+			Name:      &other,
+		}, fixture)
+	})
+
+	s.Assert("merge can append slices and merge the keys of maps", func(log sugar.Log) bool {
+		fixture := Counter{Name: "counter", Cache: map[string]*SubStruct{"a": {ID: 1}}}
+		patch := Counter{Cache: map[string]*SubStruct{"a": {ID: 1}, "b": {ID: 2}}}
+		changed, err := sugar.Merge(&fixture, &patch, sugar.MergeMaps())
+		if err != nil {
+			log(err)
+			return false
+		} else if !log.Compare([]string{"Counter.Cache[b]"}, changed) {
+			return false
+		} else if len(fixture.Cache) != 2 || fixture.Cache["b"].ID != 2 || fixture.Cache["b"] == patch.Cache["b"] {
+			log("expected the keys of the cache to be merged: %+v", fixture.Cache)
+			return false
+		}
+
+		order := Order{Items: []Item{{Price: 1, Extra: "a"}}}
+		changed, err = sugar.Merge(&order, Order{Items: []Item{{Price: 2, Extra: "b"}}}, sugar.AppendSlices())
+		if err != nil {
+			log(err)
+			return false
+		}
+		return log.Compare([]string{"Order.Items"}, changed) && log.Compare([]Item{{Price: 1, Extra: "a"}, {Price: 2, Extra: "b"}}, order.Items)
+	})
+
+}
+
+type Version struct {
+	Major int
+	Minor int
+	Label string
+}
+
+func (v Version) Equal(other Version) bool {
+	return v.Major == other.Major && v.Minor == other.Minor
 }
+
+type Money struct {
+	Cents    int64
+	Currency string
+}
+
+type Release struct {
+	Version Version
+	Price   *Money
+}
+
+type Kinds struct {
+	Grid     [2][]int
+	Value    interface{}
+	Callback func()
+	Events   chan int
+	Tags     []string
+	Labels   map[string]string
+}
+
+func TestCompare(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Assert("compare logs missing keys, unexpected keys and different values in maps", func(log sugar.Log) bool {
+		logger := sugar.NewLogger()
+		if sugar.Log(logger.Log).Compare(map[string]int{"a": 1, "b": 2, "c": 3}, map[string]int{"a": 1, "b": 3, "d": 4}) {
+			log("expected the maps to be different")
+			return false
+		} else if !sugar.Log(logger.Log).Compare(map[string]int{"a": 1}, map[string]int{"a": 1}) {
+			log("expected the maps to be the same")
+			return false
+		}
+		output := logger.String()
+		for _, expected := range []string{
+			"map[string]int[b]",
+			"map[string]int[c] is missing",
+			"map[string]int[d] was not expected",
+		} {
+			if !strings.Contains(output, expected) {
+				log("expected %q in the output", expected)
+				log(output)
+				return false
+			}
+		}
+		return strings.Index(output, "[b]") < strings.Index(output, "[c]")
+	})
+
+	s.Assert("compare logs every difference along with a count, unless it is told to stop at the first", func(log sugar.Log) bool {
+		a := []Struct{{Field: "a"}, {Field: "b", SubStruct: &SubStruct{ID: 1}}, {Field: "c"}}
+		b := []Struct{{Field: "a"}, {Field: "x", SubStruct: &SubStruct{ID: 2}}, {Field: "y"}}
+
+		logger := sugar.NewLogger()
+		if logger.Compare(a, b) {
+			log("expected the slices to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "3 differences") ||
+			!strings.Contains(output, "Struct[1].Field") || !strings.Contains(output, "Struct[2].Field") {
+			log("expected 3 differences at index 1 and 2")
+			log(output)
+			return false
+		}
+
+		logger = sugar.NewLogger()
+		if logger.Compare(a, b, sugar.StopAtFirstDifference()) {
+			log("expected the slices to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "1 difference") ||
+			strings.Contains(output, "Struct[2].Field") {
+			log("expected only the first difference")
+			log(output)
+			return false
+		}
+		return true
+	})
+
+	s.Assert("diff returns the path, kind and values of every difference", func(log sugar.Log) bool {
+		a := Order{Items: []Item{{Price: 1, Extra: "a"}, {Price: 2, Extra: "b"}}}
+		b := Order{Items: []Item{{Price: 1, Extra: 1}, {Price: 3, Extra: "b"}, {Price: 4}}}
+		return log.Compare([]sugar.Difference{{
+			Path:     ".Items[0].Extra",
+			Kind:     sugar.TypeMismatch,
+			Expected: "a",
+			Found:    1,
+		}, {
+			Path:     ".Items[1].Price",
+			Kind:     sugar.Changed,
+			Expected: 2.0,
+			Found:    3.0,
+		}, {
+			Path:  ".Items[2]",
+			Kind:  sugar.Added,
+			Found: Item{Price: 4},
+		}}, sugar.Diff(a, b))
+	})
+
+	s.Assert("compare compares unexported fields, unless it is told to ignore them", func(log sugar.Log) bool {
+		a := Counter{Name: "counter"}
+		b := Counter{Name: "counter"}
+		var copied Counter
+		if err := sugar.Copy(&Counter{count: 1}, &copied, sugar.CopyUnexported()); err != nil {
+			log(err)
+			return false
+		}
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.count = copied.Count()
+
+		differences := sugar.Diff(map[string]*Counter{"a": &a}, map[string]*Counter{"a": &b})
+		if len(differences) != 2 || !strings.HasPrefix(differences[0].Path, "[a].mutex.") || differences[1].Path != "[a].count" {
+			log("expected the mutex state and the count to be different")
+			log(differences)
+			return false
+		}
+
+		logger := sugar.NewLogger()
+		if !logger.Compare(&a, &b, sugar.IgnoreUnexported()) {
+			log("expected unexported fields to be ignored")
+			return false
+		}
+		return strings.Contains(logger.String(), "ignored 2 unexported fields")
+	})
+
+	s.Assert("compare options ignore paths, types and zero values, and set float and time tolerances", func(log sugar.Log) bool {
+		now := time.Now()
+		row := Row{ID: 1, Name: "row", Score: 100, UpdatedAt: now, Items: []Item{{Price: 1}, {Price: 2}}, Version: 1}
+		response := Row{ID: 2, Name: "row", Score: 100.5, UpdatedAt: now.Add(time.Minute), Items: []Item{{Price: 3}, {Price: 4}}}
+
+		if differences := sugar.Diff(row, response); len(differences) != 5 {
+			log("expected 5 differences, because Version is tagged to be ignored")
+			log(differences)
+			return false
+		}
+		if differences := sugar.Diff(row, response,
+			sugar.IgnorePaths("ID", "Items[*].Price"),
+			sugar.FloatTolerance(0, 0.01),
+			sugar.TimeTolerance(time.Hour),
+		); len(differences) != 0 {
+			log("expected the ignored paths and the tolerances to hide every difference")
+			log(differences)
+			return false
+		}
+		if differences := sugar.Diff(Row{Name: "row", Items: []Item{{}, {}}}, response,
+			sugar.IgnoreZeroExpected(),
+			sugar.IgnoreTypes(time.Time{}, 0.0),
+		); len(differences) != 0 {
+			log("expected the zero values and the ignored types to hide every difference")
+			log(differences)
+			return false
+		}
+		return true
+	})
+
+	s.Assert("compare ignores the order of slices, and can sort them by a key first", func(log sugar.Log) bool {
+		a := []Item{{Price: 1}, {Price: 2, Extra: "b"}, {Price: 3}}
+		b := []Item{{Price: 3}, {Price: 2, Extra: "x"}, {Price: 1}, {Price: 4}}
+
+		if differences := sugar.Diff(a, b, sugar.IgnoreOrder()); len(differences) != 2 ||
+			differences[0].Path != "[1].Extra" || differences[1].Path != "[3]" || differences[1].Kind != sugar.Added {
+			log("expected the nearest match to [1] and the unexpected [3]")
+			log(differences)
+			return false
+		}
+		order := Order{Items: []Item{{Price: 1}, {Price: 2}}}
+		if differences := sugar.Diff(order, Order{Items: []Item{{Price: 2}, {Price: 1}}}, sugar.IgnoreOrder("Items")); len(differences) != 0 {
+			log("expected the items to match in any order")
+			log(differences)
+			return false
+		} else if differences := sugar.Diff([]int{1, 2}, []int{2, 1}, sugar.IgnoreOrder("Items")); len(differences) != 2 {
+			log("expected slices at other paths to be ordered")
+			log(differences)
+			return false
+		}
+		return log.Compare([]Item{{Price: 2}, {Price: 1}}, []Item{{Price: 1}, {Price: 2}},
+			sugar.SortSlicesBy(func(i Item) float64 { return i.Price }))
+	})
+
+	s.Assert("compare renders long strings as a unified diff, and binary data as a hexdump diff", func(log sugar.Log) bool {
+		lines := []string{"<html>", "<body>", "<h1>title</h1>", "<p>one</p>", "<p>two</p>", "<p>three</p>", "<p>four</p>", "</body>", "</html>"}
+		expected := strings.Join(lines, "\n")
+		lines[3] = "<p>uno</p>"
+		found := strings.Join(lines, "\n")
+
+		logger := sugar.NewLogger()
+		if logger.Compare(expected, found, sugar.DiffContext(1)) {
+			log("expected the strings to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "@@ -3,3 +3,3 @@") ||
+			!strings.Contains(output, "title") || strings.Contains(output, "<body>") {
+			log("expected a single hunk with one line of context")
+			log(output)
+			return false
+		}
+
+		logger = sugar.NewLogger()
+		if logger.Compare(Model{Body: []byte{0, 1, 2, 3}}, Model{Body: []byte{0, 1, 2, 4}}) {
+			log("expected the bodies to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "Model.Body") || !strings.Contains(output, "00000000") {
+			log("expected a hexdump diff of the body")
+			log(output)
+			return false
+		}
+		return true
+	})
+
+	s.Assert("compare json ignores key order and whitespace, and logs differences by their json pointer", func(log sugar.Log) bool {
+		expected := `{"id": 1, "items": [{"name": "a"}, {"name": "b"}, {"name": "c/d"}]}`
+		if !log.CompareJSON(expected, []byte(`{"items":[{"name":"a"},{"name":"b"},{"name":"c/d"}],"id":1}`)) {
+			return false
+		}
+
+		logger := sugar.NewLogger()
+		if logger.CompareJSON(expected, map[string]interface{}{"id": 2, "items": []map[string]string{{"name": "a"}, {"name": "x"}}}) {
+			log("expected the documents to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "/id") || !strings.Contains(output, "/items/1/name") ||
+			!strings.Contains(output, "/items/2 is missing") {
+			log("expected differences at /id, /items/1/name and /items/2")
+			log(output)
+			return false
+		}
+
+		if !log.CompareJSON(expected, `{"id": 3, "items": [{"name": "c/d"}, {"name": "b"}, {"name": "a"}]}`,
+			sugar.IgnorePaths("/id"), sugar.IgnoreOrder("/items")) {
+			return false
+		} else if logger := sugar.NewLogger(); logger.CompareJSON(expected, `{"id": `) ||
+			!strings.Contains(logger.String(), "could not decode the actual JSON") {
+			log("expected invalid json to be logged")
+			return false
+		}
+
+		if logger := sugar.NewLogger(); logger.CompareJSON(`{"id": 9007199254740993}`, `{"id": 9007199254740992}`) {
+			log("expected large numbers to be compared exactly")
+			return false
+		}
+		return log.CompareJSON(`{"price": 1.0, "count": 1e2}`, `{"price": 1, "count": 100}`) &&
+			log.CompareJSON(`{"price": 1.0}`, `{"price": 1.001}`, sugar.FloatTolerance(0.01, 0))
+	})
+
+	s.Assert("compare uses equal methods and registered comparers, and logs which one it used", func(log sugar.Log) bool {
+		a := Release{Version: Version{Major: 1, Minor: 2, Label: "beta"}, Price: &Money{Cents: 100, Currency: "usd"}}
+		if !log.Compare(a, Release{Version: Version{Major: 1, Minor: 2, Label: "rc"}, Price: &Money{Cents: 100, Currency: "usd"}}) {
+			log("expected the labels to be ignored by Version.Equal")
+			return false
+		}
+
+		sugar.RegisterComparer(func(a, b Money) bool {
+			return a.Cents == b.Cents && strings.EqualFold(a.Currency, b.Currency)
+		})
+		b := Release{Version: Version{Major: 1, Minor: 3}, Price: &Money{Cents: 100, Currency: "USD"}}
+		if differences := sugar.Diff(a, b); len(differences) != 1 || differences[0].Path != ".Version" ||
+			differences[0].Comparer != "(sugar_test.Version).Equal" {
+			log("expected the version to be different and the currencies to be equal")
+			log(differences)
+			return false
+		}
+
+		logger := sugar.NewLogger()
+		if logger.Compare(a.Price, b.Price, sugar.UseComparer(func(a, b Money) bool { return a == b })) {
+			log("expected the comparer passed in to be used instead of the registered one")
+			return false
+		}
+		return strings.Contains(logger.String(), "compared by")
+	})
+
+	s.Assert("compare subset only checks the values that are set in expected and logs them", func(log sugar.Log) bool {
+		actual := Row{ID: 7, Name: "row", UpdatedAt: time.Now(), Items: []Item{{Price: 1, Extra: "a"}, {Price: 2}, {Price: 3}}}
+		if !log.CompareSubset(Row{Name: "row", Items: []Item{{Extra: "a"}, {Price: 2}}}, actual) {
+			return false
+		}
+
+		logger := sugar.NewLogger()
+		if logger.CompareSubset(map[string]interface{}{"name": "row", "id": 8}, map[string]interface{}{"name": "row", "id": 7, "extra": true}) {
+			log("expected the ids to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "checked 2 values") ||
+			!strings.Contains(output, "[name]") || !strings.Contains(output, "1 difference") || strings.Contains(output, "[extra]") {
+			log("expected both keys to be checked, and only the id to be different")
+			log(output)
+			return false
+		}
+		return true
+	})
+
+	s.Assert("compare renders differences side by side or compactly when the logger is set to", func(log sugar.Log) bool {
+		a := []Struct{{Field: "a"}, {Field: "b", SubStructs: []SubStruct{{ID: 1}, {ID: 2}}}}
+		b := []Struct{{Field: "a"}, {Field: "b", SubStructs: []SubStruct{{ID: 1}, {ID: 3}}}}
+
+		logger := sugar.NewLogger()
+		logger.SetStyle(sugar.SideBySideStyle)
+		if logger.Compare(a, b) {
+			log("expected the slices to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "[]sugar_test.Struct[1].SubStructs[1].ID") ||
+			!strings.Contains(output, "expected") {
+			log("expected a row for the different id")
+			log(output)
+			return false
+		}
+
+		columns, isSet := os.LookupEnv("COLUMNS")
+		for _, width := range []string{"16", "10", "1"} {
+			os.Setenv("COLUMNS", width)
+			logger = sugar.NewLogger()
+			logger.SetStyle(sugar.SideBySideStyle)
+			logger.Compare(a, b)
+			if !strings.Contains(logger.String(), "…") {
+				log("expected the rows to be cut down to fit %s columns", width)
+				log(logger)
+				return false
+			}
+		}
+		if isSet {
+			os.Setenv("COLUMNS", columns)
+		} else {
+			os.Unsetenv("COLUMNS")
+		}
+
+		logger = sugar.NewLogger()
+		logger.SetStyle(sugar.CompactStyle)
+		if logger.Compare(a, b) {
+			log("expected the slices to be different")
+			return false
+		} else if output := logger.String(); !strings.Contains(output, "[]sugar_test.Struct[1].SubStructs[1].ID: changed, expected 2.(uint), found 3.(uint)") {
+			log("expected a single line for the different id")
+			log(output)
+			return false
+		}
+
+		var style sugar.Style
+		return style.Set("compact") == nil && style == sugar.CompactStyle && style.Set("fancy") != nil
+	})
+
+	s.Assert("compare handles every kind, and reports different types inside of interfaces", func(log sugar.Log) bool {
+		events := make(chan int)
+		callback := func() {}
+		a := Kinds{Grid: [2][]int{{1}, {2}}, Value: 1, Callback: callback, Events: events, Tags: []string{}}
+		b := Kinds{Grid: [2][]int{{1}, {3}}, Value: "1", Callback: callback, Events: events, Labels: map[string]string{}}
+
+		differences := sugar.Diff(a, b)
+		if len(differences) != 2 || differences[0].Path != ".Grid[1][0]" || differences[1].Path != ".Value" ||
+			differences[1].Kind != sugar.TypeMismatch {
+			log("expected the grid and the type of the value to be different")
+			log(differences)
+			return false
+		}
+
+		b.Callback, b.Events = func() { callback() }, make(chan int)
+		if differences := sugar.Diff(a, b, sugar.DistinguishNil()); len(differences) != 6 {
+			log("expected the callback, the events, and the nil and empty tags and labels to be different too")
+			log(differences)
+			return false
+		}
+		logger := sugar.NewLogger()
+		return !logger.Compare(1, "1") && !logger.Compare(a, &b) && !logger.Compare(nil, a)
+	})
+
+	s.Assert("compare stops at cycles and can be limited to a depth", func(log sugar.Log) bool {
+		tree := func(name string) *Node {
+			root := &Node{Name: "root"}
+			child := &Node{Name: name, Parent: root}
+			child.Sibling = child
+			root.Children = []*Node{child, {Name: "other", Parent: root}}
+			return root
+		}
+		if !log.Compare(tree("child"), tree("child")) {
+			return false
+		} else if differences := sugar.Diff(tree("child"), tree("changed")); len(differences) != 1 ||
+			differences[0].Path != ".Children[0].Name" {
+			log("expected only the name of the child to be different")
+			log(differences)
+			return false
+		}
+
+		values := []interface{}{1, nil}
+		values[1] = values
+		if differences := sugar.Diff(values, values); len(differences) != 0 {
+			log(differences)
+			return false
+		}
+
+		a, b := &Node{Name: "a"}, &Node{Name: "a"}
+		for i := 0; i < 10; i++ {
+			a, b = &Node{Sibling: a}, &Node{Sibling: b}
+		}
+		b.Sibling.Sibling.Name = "b"
+		return len(sugar.Diff(a, b)) == 1 && len(sugar.Diff(a, b, sugar.MaxCompareDepth(1))) == 0
+	})
+
+}
+
+func TestExpect(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Assert("expectations match values against matchers", func(log sugar.Log) bool {
+		err := fmt.Errorf("wrapped: %w", errTest)
+		log.Expect(Struct{Field: "a"}).To(sugar.Equal(Struct{Field: "a"}))
+		log.Expect([]int{1, 2, 5}).To(sugar.And(sugar.HaveLen(3), sugar.ContainElement(sugar.BeNumerically(">", 4))))
+		log.Expect(map[string]int{"a": 1}).To(sugar.HaveKey(sugar.MatchRegexp("^a$")))
+		log.Expect(map[string]int{"a": 1}).ToNot(sugar.Or(sugar.HaveKey("b"), sugar.ContainElement(2)))
+		log.Expect((*Struct)(nil)).To(sugar.BeNil())
+		log.Expect(Struct{}).To(sugar.Not(sugar.BeNil()))
+		log.Expect(Struct{}).To(sugar.BeZero())
+		log.Expect(err).To(sugar.MatchError(errTest))
+		log.Expect(err).To(sugar.MatchError("wrapped: test"))
+		log.Expect(uint8(3)).To(sugar.BeNumerically("<=", 3.0))
+		log.Expect(int64(math.MaxInt64 - 1)).ToNot(sugar.BeNumerically("==", int64(math.MaxInt64)))
+		log.Expect(uint64(math.MaxUint64)).To(sugar.BeNumerically(">", uint64(math.MaxUint64-1)))
+		log.Expect(int64(-1)).To(sugar.BeNumerically("<", uint64(math.MaxUint64)))
+		return log.Expect(true).To(sugar.BeTrue())
+	})
+
+	s.Assert("expectations that aren't met log why and fail the test, even if it returns true", func(log sugar.Log) bool {
+		var output bytes.Buffer
+		main := sugar.New(nil, &output)
+		main.Assert("a failing expectation", func(log sugar.Log) bool {
+			log.Expect([]int{1}).To(sugar.HaveLen(2))
+			log.Expect("value").To(sugar.BeNumerically(">", 1))
+			log.Expect("value").ToNot(sugar.BeNumerically("<", 1))
+			log.Expect(Struct{Field: "a"}).To(sugar.Equal(Struct{Field: "b"}))
+			return true
+		})
+		for _, expected := range []string{
+			"expected [1].([]int) to have a length of 2",
+			"a string isn't a number",
+			"expected value not to be < 1, but",
+			"sugar_test.Struct.Field",
+		} {
+			if !strings.Contains(output.String(), expected) {
+				log("expected %q in the output", expected)
+				log(output.String())
+				return false
+			}
+		}
+		return main.IsFailed()
+	})
+
+	s.Assert("failing tests log the expression that they returned false from", func(log sugar.Log) bool {
+		var output bytes.Buffer
+		main := sugar.New(nil, &output)
+		main.Assert("a failing test", func(log sugar.Log) bool {
+			items := []int{1}
+			return len(items) == 1 && (items[0] == 2 || items[0] == 3)
+		})
+		main.Assert("a test with a few returns", func(log sugar.Log) bool {
+			if items := []int{1}; len(items) != 1 {
+				return false
+			}
+			log("the second return is the one that fails")
+			return 1 > 2
+		})
+		var returnLine int
+		main.Assert("a test that uses constants", func(log sugar.Log) bool {
+			items := []int{1}
+			_, _, returnLine, _ = runtime.Caller(0)
+			return len(items) == 1 && maxItems*2 < 3 && true
+		})
+		if strings.Contains(output.String(), "Logger.go") {
+			log("expected the lines to be tagged with the return statements instead of with sugar's own files")
+			log(output.String())
+			return false
+		}
+		for _, expected := range []string{
+			fmt.Sprintf("[sugar_test.go:%d]", returnLine+1),
+			"returned false from len(items) == 1 && (items[0] == 2 || items[0] == 3)",
+			"returned false from 1 > 2",
+			"returned false from len(items) == 1 && maxItems*2 < 3 && true",
+			"maxItems*2 < 3 = false",
+		} {
+			if !strings.Contains(output.String(), expected) {
+				log("expected %q in the output", expected)
+				log(output.String())
+				return false
+			}
+		}
+
+		// operands that use local variables can't be evaluated, so they aren't listed again underneath the expression
+		return !strings.Contains(output.String(), "len(items) == 1 =") && !strings.Contains(output.String(), "items[0] == 2 =") &&
+			!strings.Contains(output.String(), "true = true")
+	})
+}
+
+var errTest = errors.New("test")
+
+const maxItems = 2